@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	//"log"
 	"io/ioutil"
 	//"strconv"
@@ -37,6 +41,8 @@ func main() {
 
 	// prepare notifier
 	notifer := domain.NewNotifer()
+	notifer.Http = result.Http
+	notifer.Checks = result.Checks
 	for name, alerter := range result.Alerters {
 		notifer.AddAlerter(name, alerter)
 	}
@@ -50,7 +56,30 @@ func main() {
 	for _, monitor := range result.Monitors {
 		fuse.AddMonitor(monitor)
 	}
+	fuse.Heartbeat = result.Heartbeat
 
-	// start monitor's gorutines and wait
-	fuse.RunWith(notifer)
+	// start monitor's gorutines and wait for error or termination signal
+	errs := make(chan error, 1)
+	go func() {
+		errs <- fuse.RunWith(notifer)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case err := <-errs:
+		fmt.Fprintln(os.Stderr, "error during starting fuse:", err)
+		os.Exit(1)
+	case sig := <-signals:
+		log.WithField("signal", sig).Info("main: shutting down")
+	}
+
+	// drain in-flight slack/twilio callbacks
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := notifer.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("main: error during graceful shutdown")
+	}
 }
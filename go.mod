@@ -3,12 +3,16 @@ module fuse
 go 1.12
 
 require (
+	github.com/go-sql-driver/mysql v1.5.0
 	github.com/hashicorp/consul/api v1.2.0
 	github.com/influxdata/influxdb v1.7.8
+	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v1.11.0
 	github.com/nlopes/slack v0.6.0
 	github.com/parnurzeal/gorequest v0.2.16
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.3.0
 	github.com/yhirose/go-peg v0.0.0-20190710015414-7eb2cf046928
+	golang.org/x/net v0.0.0-20181201002055-351d144fa1fc
 	moul.io/http2curl v1.0.0 // indirect
 )
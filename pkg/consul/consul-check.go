@@ -0,0 +1,146 @@
+package consul
+
+import (
+	"sort"
+
+	"github.com/hashicorp/consul/api"
+
+	"fuse/pkg/domain"
+)
+
+const MAX_PREVIEW_INSTANCES = 10 // failing instances go first
+
+/*
+ * Exposes consul service as check for chat-ops.
+ */
+type serviceCheck struct {
+	consul  *Consul
+	service *Service
+}
+
+/*
+ * Exposes key of consul's KV storage as check for chat-ops.
+ */
+type keyCheck struct {
+	consul *Consul
+	key    *Key
+}
+
+/*
+ * CheckSource interface implementation.
+ */
+func (c *Consul) GetChecks() []domain.Check {
+	checks := make([]domain.Check, 0, len(c.Services)+len(c.Keys))
+	for _, service := range c.Services {
+		checks = append(checks, &serviceCheck{c, service})
+	}
+	for _, key := range c.Keys {
+		checks = append(checks, &keyCheck{c, key})
+	}
+	return checks
+}
+
+func (c *serviceCheck) GetReportId() string {
+	return c.service.GetReportId()
+}
+
+func (c *serviceCheck) GetName() string {
+	if c.service.isStatusMode() {
+		return c.service.Name
+	}
+	return c.service.Name + " " + c.service.Mode
+}
+
+func (c *serviceCheck) GetMonitor() string {
+	return c.consul.GetName()
+}
+
+func (c *serviceCheck) GetQuery() string {
+	return ""
+}
+
+func (c *serviceCheck) GetTrigger() *domain.Trigger {
+	return domain.ReadyTrigger(c.consul.GetLastLoop(), c.service.Trigger)
+}
+
+func (c *serviceCheck) Run(feed bool) (interface{}, error) {
+	value, err := c.consul.getServiceValue(c.service)
+	if err != nil {
+		return nil, err
+	}
+
+	if trigger := c.GetTrigger(); feed && trigger != nil {
+		trigger.Touch(value)
+	}
+	return value, nil
+}
+
+/*
+ * Status of instances after last check (nil if service was not checked yet).
+ */
+func (c *serviceCheck) GetPreview() *domain.Table {
+	instances := c.service.GetInstances()
+	if len(instances) == 0 {
+		return nil
+	}
+
+	nodes := make([]string, 0, len(instances))
+	for node := range instances {
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		iPassing, jPassing := instances[nodes[i]] == api.HealthPassing, instances[nodes[j]] == api.HealthPassing
+		if iPassing != jPassing {
+			return jPassing
+		}
+		return nodes[i] < nodes[j]
+	})
+
+	table := &domain.Table{Columns: []string{"node", "status"}}
+	for n, node := range nodes {
+		if n >= MAX_PREVIEW_INSTANCES {
+			table.Truncated = true
+			break
+		}
+		table.AddRow([]interface{}{node, instances[node]})
+	}
+
+	return table
+}
+
+func (c *keyCheck) GetReportId() string {
+	return c.key.GetReportId()
+}
+
+func (c *keyCheck) GetName() string {
+	return c.key.Path
+}
+
+func (c *keyCheck) GetMonitor() string {
+	return c.consul.GetName()
+}
+
+func (c *keyCheck) GetQuery() string {
+	return "kv://" + c.key.Path
+}
+
+func (c *keyCheck) GetTrigger() *domain.Trigger {
+	return domain.ReadyTrigger(c.consul.GetLastLoop(), c.key.Trigger)
+}
+
+func (c *keyCheck) Run(feed bool) (interface{}, error) {
+	value, err := c.consul.getKeyValue(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if trigger := c.GetTrigger(); feed && trigger != nil {
+		trigger.Touch(value)
+	}
+	return value, nil
+}
+
+func (c *keyCheck) GetPreview() *domain.Table {
+	return nil
+}
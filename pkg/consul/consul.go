@@ -6,17 +6,29 @@ import (
 	"github.com/hashicorp/consul/api"
 	log "github.com/sirupsen/logrus"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"fuse/pkg/domain"
+	"fuse/pkg/monitor"
 )
 
 //import "github.com/davecgh/go-spew/spew"
 
+const (
+	MODE_STATUS = "status" // "online" if all instances pass health checks, "offline" otherwise
+	MODE_COUNT  = "count"  // amount of passing instances
+	MODE_RATIO  = "ratio"  // passing instances to all registered ones (0 if there are no instances)
+)
+
 type Consul struct {
+	monitor.Pulse
+
 	Services []*Service
+	Keys     []*Key
 
 	client  *api.Client     // consul api client
 	notifer *domain.Notifer // notifer to send alters to
@@ -26,17 +38,60 @@ type Consul struct {
 
 type Service struct {
 	Name    string
+	Mode    string // MODE_STATUS (also if empty, see isStatusMode), MODE_COUNT or MODE_RATIO
 	Alerts  []string
 	Trigger *domain.Trigger
+
+	mu        sync.Mutex
+	instances map[string]string // status of instances by node after last check
+}
+
+/*
+ * Value of consul's KV storage. Numeric values are compared as floats, missing key is <nil>.
+ */
+type Key struct {
+	Path    string
+	Alerts  []string
+	Trigger *domain.Trigger
+}
+
+/*
+ * Service without mode is checked in MODE_STATUS.
+ */
+func (s *Service) isStatusMode() bool {
+	return s.Mode == "" || s.Mode == MODE_STATUS
 }
 
 func (s *Service) GetReportId() string {
 	h := md5.New()
 	io.WriteString(h, s.Name)
+	if !s.isStatusMode() {
+		io.WriteString(h, "|"+s.Mode)
+	}
 	return fmt.Sprintf("%.5x", h.Sum(nil))
 }
 
-func NewConsul(services []*Service, options map[string]string) *Consul {
+/*
+ * Returns status of instances by node after last check.
+ */
+func (s *Service) GetInstances() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instances := make(map[string]string, len(s.instances))
+	for node, status := range s.instances {
+		instances[node] = status
+	}
+	return instances
+}
+
+func (k *Key) GetReportId() string {
+	h := md5.New()
+	io.WriteString(h, "kv|"+k.Path)
+	return fmt.Sprintf("%.5x", h.Sum(nil))
+}
+
+func NewConsul(services []*Service, keys []*Key, options map[string]string) *Consul {
 	optionsFull := map[string]string{
 		"url":      "localhost:8500",
 		"interval": "5",
@@ -61,6 +116,7 @@ func NewConsul(services []*Service, options map[string]string) *Consul {
 	return &Consul{
 		client:   client,
 		Services: services,
+		Keys:     keys,
 		options:  optionsFull,
 	}
 }
@@ -82,62 +138,155 @@ func (c *Consul) RunWith(notifer *domain.Notifer) {
 	for {
 		log.Info("consul: check loop...")
 		c.checkServices()
+		c.checkKeys()
+		c.Beat(time.Duration(interval) * time.Second)
 		time.Sleep(time.Duration(interval) * time.Second)
 	}
 }
 
 func (c *Consul) addTriggers(interval int) {
-	mainAlert := c.options["alert"]
-
 	for _, service := range c.Services {
 		if service.Trigger == nil {
-			service.Trigger = c.defaultTrigger()
+			service.Trigger = c.defaultTrigger(service.Mode)
+		}
+
+		service.Trigger.SetupNilStates()
+		service.Trigger.Callback = c.makeServiceCallback(service, interval)
+	}
+
+	for _, key := range c.Keys {
+		key.Trigger.SetupNilStates()
+		key.Trigger.Callback = c.makeKeyCallback(key, interval)
+	}
+}
+
+func (c *Consul) makeServiceCallback(service *Service, interval int) func(*domain.State, interface{}) error {
+	return func(state *domain.State, lastValue interface{}) error {
+		duration := time.Duration(interval*state.Cycles) * time.Second
+		instances := service.GetInstances()
+
+		var body string
+		switch {
+		case !service.isStatusMode():
+			body = fmt.Sprintf("Service \"%s\" has %s instances passing more than %s.", service.Name, formatPassing(instances), duration)
+		case state.Name == "good":
+			body = fmt.Sprintf("Service \"%s\" is online more than %s.", service.Name, duration)
+		default:
+			body = fmt.Sprintf("Service \"%s\" is offline more than %s.", service.Name, duration)
 		}
 
-		// create local var for closure function
-		service := service
+		// status of every failing instance by its node
+		details := map[string]string{
+			"value":   fmt.Sprintf("%v", lastValue),
+			"passing": formatPassing(instances),
+		}
 
-		service.Trigger.Callback = func(state *domain.State, lastValue interface{}) error {
-			var alive string
-			if state.Name == "good" {
-				alive = "online"
-			} else {
-				alive = "offline"
+		failing := make([]string, 0)
+		for node, status := range instances {
+			if status != api.HealthPassing {
+				failing = append(failing, node)
+				details[node] = status
 			}
+		}
+		sort.Strings(failing)
 
-			title := fmt.Sprintf("SERVICE: *%s* in %s state", service.Name, strings.ToUpper(state.Name))
-			body := fmt.Sprintf("Service \"%s\" is %s more than %d sec.", service.Name, alive, interval*state.Cycles)
+		if len(failing) > 0 && state.Name != "good" {
+			body += fmt.Sprintf("\nFailing instances: %s.", strings.Join(failing, ", "))
+		}
 
-			msg := domain.Message{
-				IconUrl: "https://pbs.twimg.com/media/C5SO5KRVcAA6Ag6.png", // TODO: replace
-				From:    "consul",
-				Title:   title,
-				Body:    body,
-			}
+		c.notify(state, service.Alerts, domain.Message{
+			ReportId: service.GetReportId(),
+			Title:    fmt.Sprintf("SERVICE: %s in %s state", service.Name, strings.ToUpper(state.Name)),
+			Body:     body,
+			Subject:  service.Name,
+			Duration: duration,
+			Details:  details,
+		})
 
-			msg.ParseLevel(state.Name)
+		return nil
+	}
+}
 
-			switch state.Name {
-			case "good":
-				c.notifer.Resolve(service.GetReportId())
-			default:
-				c.notifer.Report(service.GetReportId(), msg)
-			}
+func (c *Consul) makeKeyCallback(key *Key, interval int) func(*domain.State, interface{}) error {
+	return func(state *domain.State, lastValue interface{}) error {
+		duration := time.Duration(interval*state.Cycles) * time.Second
+
+		var body string
+		switch {
+		case lastValue == nil:
+			body = fmt.Sprintf("Key \"%s\" is missing more than %s.", key.Path, duration)
+		case state.Name == "good":
+			body = fmt.Sprintf("Key \"%s\" has good value more than %s.", key.Path, duration)
+		default:
+			body = fmt.Sprintf("Key \"%s\" has bad value more than %s.", key.Path, duration)
+		}
+
+		c.notify(state, key.Alerts, domain.Message{
+			ReportId: key.GetReportId(),
+			Title:    fmt.Sprintf("KV: %s in %s state", key.Path, strings.ToUpper(state.Name)),
+			Body:     body,
+			Subject:  key.Path,
+			Duration: duration,
+			Query:    "kv://" + key.Path,
+			Details:  map[string]string{"value": fmt.Sprintf("%v", lastValue)},
+		})
+
+		return nil
+	}
+}
+
+/*
+ * Sends message of service or key to main alert and optional alerts.
+ */
+func (c *Consul) notify(state *domain.State, alerts []string, msg domain.Message) {
+	msg.IconUrl = "https://pbs.twimg.com/media/C5SO5KRVcAA6Ag6.png" // TODO: replace
+	msg.From = "consul"
+	msg.ParseLevel(state.Name)
+
+	if state.Name != "good" {
+		c.notifer.Report(msg.ReportId, msg)
+	}
+
+	// always send notification to main alert
+	c.notifer.Notify(state.Name, c.options["alert"], msg)
 
-			// always send notification to main alert
-			c.notifer.Notify(state.Name, mainAlert, msg)
+	// also send alert to optional alerts
+	c.notifer.Notify(state.Name, alerts, msg)
 
-			// also send alert to optional service alerts
-			c.notifer.Notify(state.Name, service.Alerts, msg)
+	// resolve after notification, so alerters can bind recovery message to incident
+	if state.Name == "good" {
+		c.notifer.Resolve(msg.ReportId)
+	}
+}
 
-			return nil
+/*
+ * Amount of passing instances, e.g. "9/10".
+ */
+func formatPassing(instances map[string]string) string {
+	passing := 0
+	for _, status := range instances {
+		if status == api.HealthPassing {
+			passing++
 		}
 	}
+	return fmt.Sprintf("%d/%d", passing, len(instances))
 }
 
-func (c *Consul) defaultTrigger() *domain.Trigger {
+func (c *Consul) defaultTrigger(mode string) *domain.Trigger {
 	trigger := domain.NewTrigger(nil)
 
+	switch mode {
+	case MODE_COUNT:
+		trigger.AddState(&domain.State{Name: "good", Cycles: 5, Operator: ">=", Value: float64(1)})
+		trigger.AddState(&domain.State{Name: "crit", Cycles: 10, Operator: "<", Value: float64(1)})
+		return trigger
+	case MODE_RATIO:
+		trigger.AddState(&domain.State{Name: "good", Cycles: 5, Operator: ">=", Value: float64(1)})
+		trigger.AddState(&domain.State{Name: "warn", Cycles: 5, Operator: "<", Value: float64(1)})
+		trigger.AddState(&domain.State{Name: "crit", Cycles: 10, Operator: "<", Value: float64(0.5)})
+		return trigger
+	}
+
 	trigger.AddState(&domain.State{
 		Name:     "good",
 		Cycles:   5,
@@ -169,6 +318,32 @@ func (c *Consul) checkServices() {
 }
 
 func (c *Consul) checkService(service *Service) {
+	value, err := c.getServiceValue(service)
+	if err != nil {
+		log.WithError(err).WithField("service", service.Name).Error("error during api call to consul for service")
+		return
+	}
+
+	service.Trigger.Touch(value)
+}
+
+func (c *Consul) checkKeys() {
+	for _, key := range c.Keys {
+		value, err := c.getKeyValue(key)
+		if err != nil {
+			log.WithError(err).WithField("key", key.Path).Error("error during api call to consul for key")
+			continue
+		}
+
+		key.Trigger.Touch(value)
+	}
+}
+
+/*
+ * Returns value of service by its mode (see MODE_* constants) and saves status of its instances.
+ * Instance is passing if all its health checks are passing.
+ */
+func (c *Consul) getServiceValue(service *Service) (interface{}, error) {
 	log.WithFields(log.Fields{"service": service.Name}).Debug("consul : checking service")
 
 	sinfos, _, err := c.client.Health().Service(
@@ -179,36 +354,91 @@ func (c *Consul) checkService(service *Service) {
 	)
 
 	if err != nil {
-		log.WithError(err).WithField("service", service.Name).Error("error during api call to consul for service")
-		return
+		return nil, err
 	}
 
-	passing := true
+	instances := make(map[string]string, len(sinfos))
+	passing := 0
 
-	if len(sinfos) == 0 {
-		passing = false // too bad, no services with this name are registered in consul
-	}
-
-Loop:
 	for _, sinfo := range sinfos {
+		status := api.HealthPassing
 		for _, check := range sinfo.Checks {
-			if check.Status != "passing" {
-				passing = false
-				break Loop // one of found services doesn't pass health check
+			if check.Status != api.HealthPassing {
+				status = check.Status + " (" + check.Name + ")"
+				break // one of checks of instance doesn't pass
 			}
 		}
+
+		if status == api.HealthPassing {
+			passing++
+		}
+
+		instances[instanceName(sinfo)] = status
 	}
 
-	if passing {
-		service.Trigger.Touch("online")
-	} else {
-		service.Trigger.Touch("offline")
+	service.mu.Lock()
+	service.instances = instances
+	service.mu.Unlock()
+
+	if service.isStatusMode() {
+		// no services with this name are registered in consul is too bad
+		if len(sinfos) > 0 && passing == len(sinfos) {
+			return "online", nil
+		}
+		return "offline", nil
+	}
+
+	switch service.Mode {
+	case MODE_RATIO:
+		if len(sinfos) == 0 {
+			return float64(0), nil
+		}
+		return float64(passing) / float64(len(sinfos)), nil
+	default:
+		return float64(passing), nil
 	}
 }
 
+/*
+ * Name of instance: name of its node with id of service if there can be several instances on node.
+ */
+func instanceName(sinfo *api.ServiceEntry) string {
+	if sinfo.Service.ID == sinfo.Service.Service {
+		return sinfo.Node.Node
+	}
+	return sinfo.Node.Node + "/" + sinfo.Service.ID
+}
+
+/*
+ * Returns value of key as float if it is a number or as string otherwise (nil if key is missing).
+ */
+func (c *Consul) getKeyValue(key *Key) (interface{}, error) {
+	log.WithFields(log.Fields{"key": key.Path}).Debug("consul : checking key")
+
+	pair, _, err := c.client.KV().Get(key.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if pair == nil {
+		return nil, nil
+	}
+
+	value := strings.TrimSpace(string(pair.Value))
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number, nil
+	}
+	return value, nil
+}
+
 func (c *Consul) LogInfo() {
 	log.WithField("monitor", c.GetName()).WithField("amount", len(c.Services)).Info("amount of services")
 	for _, service := range c.Services {
-		log.WithField("monitor", c.GetName()).WithField("service", service.Name).Info("service")
+		log.WithField("monitor", c.GetName()).WithField("service", service.Name).WithField("mode", service.Mode).Info("service")
+	}
+
+	log.WithField("monitor", c.GetName()).WithField("amount", len(c.Keys)).Info("amount of keys")
+	for _, key := range c.Keys {
+		log.WithField("monitor", c.GetName()).WithField("key", key.Path).Info("key")
 	}
 }
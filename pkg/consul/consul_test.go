@@ -0,0 +1,193 @@
+package consul
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fuse/pkg/domain"
+)
+
+type fakeAlerter struct {
+	sent []domain.Message
+}
+
+func (a *fakeAlerter) GetName() string                         { return "fake" }
+func (a *fakeAlerter) Good(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Warn(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Crit(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Report(incident *domain.Incident) error  { return nil }
+func (a *fakeAlerter) Resolve(incident *domain.Incident) error { return nil }
+func (a *fakeAlerter) ConfigureHTTP(*http.ServeMux, string)    {}
+
+/*
+ * Fake consul with instances of "api" service (web2 fails its health check) and keys.
+ */
+var consulApi = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/v1/health/service/api":
+		w.Write([]byte(`[
+			{"Node": {"Node": "web1"}, "Service": {"ID": "api", "Service": "api"}, "Checks": [
+				{"Name": "Serf Health Status", "Status": "passing"},
+				{"Name": "api health", "Status": "passing"}
+			]},
+			{"Node": {"Node": "web2"}, "Service": {"ID": "api", "Service": "api"}, "Checks": [
+				{"Name": "Serf Health Status", "Status": "passing"},
+				{"Name": "api health", "Status": "critical"}
+			]},
+			{"Node": {"Node": "web2"}, "Service": {"ID": "api-2", "Service": "api"}, "Checks": [
+				{"Name": "Serf Health Status", "Status": "passing"}
+			]},
+			{"Node": {"Node": "web3"}, "Service": {"ID": "api", "Service": "api"}, "Checks": []}
+		]`))
+	case "/v1/health/service/missing":
+		w.Write([]byte(`[]`))
+	case "/v1/kv/queues/backlog":
+		// value is base64 encoded " 1500\n"
+		w.Write([]byte(`[{"Key": "queues/backlog", "Value": "IDE1MDAK"}]`))
+	case "/v1/kv/maintenance":
+		// value is base64 encoded "on"
+		w.Write([]byte(`[{"Key": "maintenance", "Value": "b24="}]`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+})
+
+func newConsulMonitor(server *httptest.Server, services []*Service, keys []*Key) (*Consul, *fakeAlerter) {
+	c := NewConsul(services, keys, map[string]string{"url": strings.TrimPrefix(server.URL, "http://"), "alert": "fake"})
+
+	alerter := &fakeAlerter{}
+	c.notifer = domain.NewNotifer()
+	c.notifer.AddAlerter("fake", alerter)
+	c.addTriggers(5)
+
+	return c, alerter
+}
+
+func TestServiceModes(t *testing.T) {
+	server := httptest.NewServer(consulApi)
+	defer server.Close()
+
+	status := &Service{Name: "api", Mode: MODE_STATUS}
+	count := &Service{Name: "api", Mode: MODE_COUNT}
+	ratio := &Service{Name: "api", Mode: MODE_RATIO}
+	missing := &Service{Name: "missing", Mode: MODE_RATIO}
+	c, _ := newConsulMonitor(server, []*Service{status, count, ratio, missing}, nil)
+
+	value, err := c.getServiceValue(status)
+	assert.NoError(t, err)
+	assert.Equal(t, "offline", value)
+
+	value, _ = c.getServiceValue(count)
+	assert.Equal(t, float64(3), value)
+
+	value, _ = c.getServiceValue(ratio)
+	assert.Equal(t, 0.75, value)
+
+	value, _ = c.getServiceValue(missing)
+	assert.Equal(t, float64(0), value, "no instances")
+
+	assert.Equal(t, map[string]string{
+		"web1":       "passing",
+		"web2":       "critical (api health)",
+		"web2/api-2": "passing",
+		"web3":       "passing",
+	}, ratio.GetInstances())
+
+	assert.Len(t, map[string]bool{status.GetReportId(): true, count.GetReportId(): true, ratio.GetReportId(): true}, 3)
+	assert.Equal(t, (&Service{Name: "api"}).GetReportId(), status.GetReportId(), "report id of status mode is not changed")
+}
+
+func TestServiceAlerts(t *testing.T) {
+	server := httptest.NewServer(consulApi)
+	defer server.Close()
+
+	service := &Service{Name: "api", Mode: MODE_RATIO}
+	c, alerter := newConsulMonitor(server, []*Service{service}, nil)
+
+	for n := 0; n < 5; n++ {
+		c.checkServices()
+	}
+
+	assert.Len(t, alerter.sent, 1)
+	msg := alerter.sent[0]
+	assert.Equal(t, "SERVICE: api in WARN state", msg.Title)
+	assert.Equal(t, "Service \"api\" has 3/4 instances passing more than 25s.\nFailing instances: web2.", msg.Body)
+	assert.Equal(t, map[string]string{"value": "0.75", "passing": "3/4", "web2": "critical (api health)"}, msg.Details)
+
+	checks := c.GetChecks()
+	assert.Equal(t, "api ratio", checks[0].GetName())
+	assert.Equal(t, []string{"web2", "critical (api health)"}, checks[0].GetPreview().Rows[0], "failing instances go first")
+}
+
+func TestServiceWithoutMode(t *testing.T) {
+	server := httptest.NewServer(consulApi)
+	defer server.Close()
+
+	trigger := domain.NewTrigger(nil)
+	trigger.AddState(&domain.State{Name: "good", Cycles: 1, Operator: "=", Value: "online"})
+	trigger.AddState(&domain.State{Name: "crit", Cycles: 1, Operator: "=", Value: "offline"})
+
+	service := &Service{Name: "api", Trigger: trigger}
+	c, alerter := newConsulMonitor(server, []*Service{service}, nil)
+	c.checkServices()
+
+	assert.Len(t, alerter.sent, 1)
+	assert.Equal(t, "Service \"api\" is offline more than 5s.\nFailing instances: web2.", alerter.sent[0].Body, "empty mode is status")
+	assert.Equal(t, "api", c.GetChecks()[0].GetName())
+}
+
+func TestKeys(t *testing.T) {
+	server := httptest.NewServer(consulApi)
+	defer server.Close()
+
+	backlog := domain.NewTrigger(nil)
+	backlog.AddState(&domain.State{Name: "good", Cycles: 1, Operator: "<", Value: float64(1000)})
+	backlog.AddState(&domain.State{Name: "crit", Cycles: 1, Operator: ">=", Value: float64(1000)})
+
+	maintenance := domain.NewTrigger(nil)
+	maintenance.AddState(&domain.State{Name: "good", Cycles: 1, Operator: "=", Value: "off"})
+	maintenance.AddState(&domain.State{Name: "warn", Cycles: 1, Operator: "=", Value: "on"})
+
+	missing := domain.NewTrigger(nil)
+	missing.AddState(&domain.State{Name: "good", Cycles: 1, Operator: "=", Value: "on"})
+	missing.AddState(&domain.State{Name: "crit", Cycles: 1, Operator: "=", Value: "off"})
+
+	keys := []*Key{
+		{Path: "queues/backlog", Trigger: backlog},
+		{Path: "maintenance", Trigger: maintenance},
+		{Path: "feature/flag", Trigger: missing},
+	}
+	c, alerter := newConsulMonitor(server, nil, keys)
+
+	value, err := c.getKeyValue(keys[0])
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1500), value, "numbers are floats")
+
+	value, _ = c.getKeyValue(keys[1])
+	assert.Equal(t, "on", value)
+
+	value, err = c.getKeyValue(keys[2])
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	c.checkKeys()
+	assert.Len(t, alerter.sent, 3)
+	assert.Equal(t, "KV: queues/backlog in CRIT state", alerter.sent[0].Title)
+	assert.Equal(t, "Key \"queues/backlog\" has bad value more than 5s.", alerter.sent[0].Body)
+	assert.Equal(t, "KV: maintenance in WARN state", alerter.sent[1].Title)
+	assert.Equal(t, "Key \"feature/flag\" is missing more than 5s.", alerter.sent[2].Body, "missing key triggers crit state")
+
+	_, ok := c.notifer.Incidents.Get(keys[0].GetReportId())
+	assert.True(t, ok)
+
+	checks := c.GetChecks()
+	assert.Len(t, checks, 3)
+	assert.Equal(t, "maintenance", checks[1].GetName())
+	assert.Equal(t, "kv://maintenance", checks[1].GetQuery())
+}
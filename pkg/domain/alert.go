@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -9,6 +11,13 @@ import (
 type Notifer struct {
 	Alerters map[string]Alerter
 	Metrics  map[string]Metric
+
+	Incidents *Incidents // active reports
+	Checks    *Checks    // checks of monitors for http api (optional)
+
+	Http   HttpOptions
+	mux    *http.ServeMux // dedicated mux for alerter's callbacks
+	server *http.Server
 }
 
 const (
@@ -23,13 +32,20 @@ type Message struct {
 	Level int
 
 	// context info (consul/influx/...)
-	IconUrl string
-	From    string
+	IconUrl  string
+	From     string
+	ReportId string // id of check's report (empty for service messages)
 
-	// message
+	// message (plain text, alerters apply their own formatting)
 	Title string
 	Body  string
 
+	// structured data of check for templates
+	Subject  string        // name of check (service, query, ...)
+	Duration time.Duration // how long check is in current state
+	Query    string        // query of check (empty if monitor has no queries)
+	Preview  *Table        // result of preview query (nil if not available)
+
 	// additional info as field-value pairs
 	Details map[string]string
 	Args    map[string]interface{}
@@ -42,16 +58,29 @@ type Alerter interface {
 	Warn(msg Message) error
 	Crit(msg Message) error
 
-	Report(reportId string, msg Message) error
-	Resolve(reportId string) error
+	Report(incident *Incident) error
+	Resolve(incident *Incident) error
+
+	// register http callbacks; all paths must be prefixed with basePath
+	ConfigureHTTP(mux *http.ServeMux, basePath string)
+}
 
-	ConfigureHTTP()
+/*
+ * Alerter with its own outbound connection (e.g. slack RTM) which is
+ * opened and closed together with http listener of Notifer.
+ */
+type Connector interface {
+	Connect() error
+	Disconnect() error
 }
 
 func NewNotifer() *Notifer {
 	return &Notifer{
-		Alerters: make(map[string]Alerter),
-		Metrics:  make(map[string]Metric),
+		Alerters:  make(map[string]Alerter),
+		Metrics:   make(map[string]Metric),
+		Incidents: NewIncidents(),
+		Http:      DefaultHttpOptions(),
+		mux:       http.NewServeMux(),
 	}
 }
 
@@ -72,7 +101,7 @@ func (n *Notifer) Notify(level string, channels interface{}, msg Message) {
 	switch level {
 	case "good":
 		n.Good(channels, msg)
-	case "warn":
+	case "warn", "unknown":
 		n.Warn(channels, msg)
 	case "crit":
 		n.Crit(channels, msg)
@@ -100,6 +129,12 @@ func (n *Notifer) Good(channels interface{}, msg Message) {
 
 func (n *Notifer) Warn(channels interface{}, msg Message) {
 	msg.Level = MSG_LVL_WARN
+	if n.isSilenced(msg) {
+		log.WithField("report", msg.ReportId).Info("alert: incident is silenced, skipping message")
+		n.sendMetrics(msg)
+		return
+	}
+
 	n.notifyOneOrMany(channels, func(alerter Alerter) error {
 		log.WithField("channel", alerter.GetName()).Info("alert: send Warn message")
 		log.WithField("msg", msg).Debug("alert: message")
@@ -116,6 +151,12 @@ func (n *Notifer) Warn(channels interface{}, msg Message) {
 
 func (n *Notifer) Crit(channels interface{}, msg Message) {
 	msg.Level = MSG_LVL_CRIT
+	if n.isSilenced(msg) {
+		log.WithField("report", msg.ReportId).Info("alert: incident is silenced, skipping message")
+		n.sendMetrics(msg)
+		return
+	}
+
 	n.notifyOneOrMany(channels, func(alerter Alerter) error {
 		log.WithField("channel", alerter.GetName()).Info("alert: send Crit message")
 		log.WithField("msg", msg).Debug("alert: message")
@@ -130,37 +171,88 @@ func (n *Notifer) Crit(channels interface{}, msg Message) {
 	n.sendMetrics(msg)
 }
 
-func (n *Notifer) Start() {
+/*
+ * Configures alerters, opens their connections and starts http listener in background.
+ */
+func (n *Notifer) Start() error {
 	for name, alerter := range n.Alerters {
-		name, alerter := name, alerter
 		log.WithField("name", name).Info("notifer: configuring alerter")
-		alerter.ConfigureHTTP()
+		alerter.ConfigureHTTP(n.mux, n.Http.GetBasePath())
+
+		if connector, ok := alerter.(Connector); ok {
+			if err := connector.Connect(); err != nil {
+				return err
+			}
+		}
+	}
+	n.configureApi(n.mux, n.Http.GetBasePath())
+
+	server, err := n.Http.serve(n.mux)
+	if err != nil {
+		return err
 	}
+	n.server = server
+
+	log.WithField("listen", n.Http.Listen).Info("notifer: http listener started")
+	return nil
+}
 
-	// TODO: configurable port?
-	go func() {
-		if err := http.ListenAndServe(":7777", nil); err != nil {
-			log.WithError(err).Fatal("notifer: can't start http listener")
+/*
+ * Closes connections of alerters and gracefully stops http listener:
+ * waits for in-flight callbacks until ctx is done.
+ */
+func (n *Notifer) Shutdown(ctx context.Context) error {
+	for name, alerter := range n.Alerters {
+		if connector, ok := alerter.(Connector); ok {
+			if err := connector.Disconnect(); err != nil {
+				log.WithError(err).WithField("alerter", name).Error("notifer: error during disconnecting alerter")
+			}
 		}
-	}()
+	}
+
+	if n.server == nil {
+		return nil
+	}
+
+	log.Info("notifer: stopping http listener")
+	return n.server.Shutdown(ctx)
 }
 
 func (n *Notifer) Report(reportId string, msg Message) {
+	incident := n.Incidents.Open(reportId, msg)
+
 	for name, alerter := range n.Alerters {
-		if err := alerter.Report(reportId, msg); err != nil {
+		if err := alerter.Report(incident); err != nil {
 			log.WithField("alerter", name).Error("notifer: error during sending report to alerter: ", err)
 		}
 	}
 }
 
 func (n *Notifer) Resolve(reportId string) {
+	incident := n.Incidents.Close(reportId)
+	if incident == nil {
+		return // nothing was reported
+	}
+
 	for name, alerter := range n.Alerters {
-		if err := alerter.Resolve(reportId); err != nil {
+		if err := alerter.Resolve(incident); err != nil {
 			log.WithField("alerter", name).Error("notifer: error during resolve report in alerter: ", err)
 		}
 	}
 }
 
+/*
+ * Checks that message belongs to incident which is silenced now.
+ */
+func (n *Notifer) isSilenced(msg Message) bool {
+	if msg.ReportId == "" {
+		return false
+	}
+
+	incident, ok := n.Incidents.Get(msg.ReportId)
+	return ok && incident.IsSilenced(time.Now())
+}
+
 func (n *Notifer) notifyOneOrMany(channels interface{}, callback func(Alerter) error) {
 	if channels, ok := channels.([]string); ok {
 		for _, channel := range channels {
@@ -197,7 +289,7 @@ func (m *Message) ParseLevel(level string) {
 	switch level {
 	case "good":
 		m.Level = MSG_LVL_GOOD
-	case "warn":
+	case "warn", "unknown":
 		m.Level = MSG_LVL_WARN
 	case "crit":
 		m.Level = MSG_LVL_CRIT
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAlerter struct {
+	sent []int
+}
+
+func (a *fakeAlerter) GetName() string                      { return "fake" }
+func (a *fakeAlerter) Good(msg Message) error               { a.sent = append(a.sent, msg.Level); return nil }
+func (a *fakeAlerter) Warn(msg Message) error               { a.sent = append(a.sent, msg.Level); return nil }
+func (a *fakeAlerter) Crit(msg Message) error               { a.sent = append(a.sent, msg.Level); return nil }
+func (a *fakeAlerter) Report(incident *Incident) error      { return nil }
+func (a *fakeAlerter) Resolve(incident *Incident) error     { return nil }
+func (a *fakeAlerter) ConfigureHTTP(*http.ServeMux, string) {}
+
+func TestNotiferSkipsSilencedIncident(t *testing.T) {
+	alerter := &fakeAlerter{}
+	n := NewNotifer()
+	n.AddAlerter("fake", alerter)
+
+	msg := Message{ReportId: "abc12"}
+	n.Report("abc12", msg)
+	incident, _ := n.Incidents.Get("abc12")
+	incident.Silence(time.Now().Add(time.Hour), "test")
+
+	n.Crit("fake", msg)
+	n.Warn("fake", Message{Title: "service message"})
+	n.Good("fake", msg)
+
+	assert.Equal(t, []int{MSG_LVL_WARN, MSG_LVL_GOOD}, alerter.sent, "only bad messages of silenced incident are skipped")
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ * Registers http api for checks; api is disabled if 'api_token' is not set.
+ *   POST {base_path}/api/run?check={report-id or name}[&feed=1]
+ */
+func (n *Notifer) configureApi(mux *http.ServeMux, basePath string) {
+	if n.Checks == nil {
+		return
+	}
+
+	if n.Http.ApiToken == "" {
+		log.Info("notifer: 'api_token' is not set, http api is disabled")
+		return
+	}
+
+	mux.HandleFunc(basePath+"/api/run", n.authorized(n.handleRun))
+}
+
+/*
+ * Checks "Authorization: Bearer {api_token}" header.
+ */
+func (n *Notifer) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(n.Http.ApiToken)) != 1 {
+			log.WithField("remote", r.RemoteAddr).Warn("notifer: rejecting api request with wrong token")
+			writeJson(w, http.StatusUnauthorized, map[string]string{"error": "wrong token"})
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func (n *Notifer) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST method"})
+		return
+	}
+
+	name := r.FormValue("check")
+	check, ok := n.Checks.Lookup(name)
+	if !ok {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "can't find check '" + name + "'"})
+		return
+	}
+
+	feed := r.FormValue("feed") == "1" || r.FormValue("feed") == "true"
+	log.WithFields(log.Fields{"check": check.GetName(), "feed": feed}).Info("notifer: running check by api request")
+
+	result, err := RunCheck(check, feed)
+	if err != nil {
+		writeJson(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJson(w, http.StatusOK, result)
+}
+
+func writeJson(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
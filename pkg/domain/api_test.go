@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCheck struct {
+	value   interface{}
+	err     error
+	trigger *Trigger
+}
+
+func (c *fakeCheck) GetReportId() string  { return "abc12" }
+func (c *fakeCheck) GetName() string      { return "api errors" }
+func (c *fakeCheck) GetMonitor() string   { return "test" }
+func (c *fakeCheck) GetQuery() string     { return "" }
+func (c *fakeCheck) GetTrigger() *Trigger { return c.trigger }
+func (c *fakeCheck) GetPreview() *Table   { return nil }
+
+func (c *fakeCheck) Run(feed bool) (interface{}, error) {
+	if feed {
+		c.trigger.Touch(c.value)
+	}
+	return c.value, c.err
+}
+
+type fakeSource []Check
+
+func (s fakeSource) GetChecks() []Check { return s }
+
+func newApiNotifer(check Check) *Notifer {
+	n := NewNotifer()
+	n.Http.ApiToken = "secret"
+	n.Checks = NewChecks()
+	n.Checks.AddSource(fakeSource{check})
+	n.configureApi(n.mux, "/fuse")
+	return n
+}
+
+func apiRequest(n *Notifer, method, url, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	n.mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestApiRunCheck(t *testing.T) {
+	trigger := NewTrigger(func(*State, interface{}) error { return nil })
+	trigger.AddState(&State{Name: "good", Cycles: 1, Value: float64(0), Operator: "="})
+	trigger.AddState(&State{Name: "crit", Cycles: 1, Value: float64(0), Operator: ">"})
+
+	n := newApiNotifer(&fakeCheck{value: float64(3), trigger: trigger})
+
+	w := apiRequest(n, "POST", "/fuse/api/run?check=abc12", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result RunResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, RunResult{ReportId: "abc12", Name: "api errors", Monitor: "test", Value: float64(3), Match: "crit", State: "good"}, result)
+
+	w = apiRequest(n, "POST", "/fuse/api/run?check=API+errors&feed=1", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"crit","fed":true`)
+	assert.Equal(t, "crit", trigger.GetState())
+}
+
+func TestApiErrors(t *testing.T) {
+	n := newApiNotifer(&fakeCheck{err: fmt.Errorf("timeout")})
+
+	assert.Equal(t, http.StatusUnauthorized, apiRequest(n, "POST", "/fuse/api/run?check=abc12", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, apiRequest(n, "POST", "/fuse/api/run?check=abc12", "wrong").Code)
+
+	r := httptest.NewRequest("POST", "/fuse/api/run?check=abc12", nil)
+	r.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	n.mux.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "token without 'Bearer' scheme must be rejected")
+	assert.Equal(t, http.StatusMethodNotAllowed, apiRequest(n, "GET", "/fuse/api/run?check=abc12", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, apiRequest(n, "POST", "/fuse/api/run?check=zzz", "secret").Code)
+
+	w = apiRequest(n, "POST", "/fuse/api/run?check=abc12", "secret")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "timeout")
+
+	// api is disabled without token
+	n = NewNotifer()
+	n.Checks = NewChecks()
+	n.configureApi(n.mux, "")
+	assert.Equal(t, http.StatusNotFound, apiRequest(n, "POST", "/api/run?check=abc12", "").Code)
+}
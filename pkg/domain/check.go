@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ * Single check of monitor (consul service, influx query, ...) available for chat-ops.
+ */
+type Check interface {
+	GetReportId() string
+	GetName() string    // human readable name of check
+	GetMonitor() string // name of monitor which owns check
+	GetQuery() string   // rendered query of check (empty if monitor has no queries)
+
+	// trigger of check (nil if monitor is not started yet)
+	GetTrigger() *Trigger
+
+	// executes check out-of-band; value is fed into check's trigger if feed == true
+	Run(feed bool) (interface{}, error)
+
+	// returns output of preview query (nil if check has no preview)
+	GetPreview() *Table
+}
+
+/*
+ * Result of out-of-band run of check.
+ */
+type RunResult struct {
+	ReportId string      `json:"report_id"`
+	Name     string      `json:"name"`
+	Monitor  string      `json:"monitor"`
+	Value    interface{} `json:"value"`
+	Match    string      `json:"match"` // state which value matches (empty if trigger is not available)
+	State    string      `json:"state"` // active state of trigger after run
+	Fed      bool        `json:"fed"`   // value was fed into trigger
+}
+
+/*
+ * Monitor which exposes its checks.
+ */
+type CheckSource interface {
+	GetChecks() []Check
+}
+
+/*
+ * Thread-safe registry of check sources; checks are requested from sources on every call,
+ * so sources can create checks on the fly.
+ */
+type Checks struct {
+	mu      sync.RWMutex
+	sources []CheckSource
+}
+
+func NewChecks() *Checks {
+	return &Checks{
+		sources: make([]CheckSource, 0),
+	}
+}
+
+func (c *Checks) AddSource(source CheckSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sources = append(c.sources, source)
+}
+
+func (c *Checks) List() []Check {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	checks := make([]Check, 0)
+	for _, source := range c.sources {
+		checks = append(checks, source.GetChecks()...)
+	}
+	return checks
+}
+
+func (c *Checks) Find(reportId string) (Check, bool) {
+	for _, check := range c.List() {
+		if check.GetReportId() == reportId {
+			return check, true
+		}
+	}
+	return nil, false
+}
+
+/*
+ * Finds check by report id or by name (case insensitive).
+ */
+func (c *Checks) Lookup(idOrName string) (Check, bool) {
+	if check, ok := c.Find(idOrName); ok {
+		return check, true
+	}
+
+	for _, check := range c.List() {
+		if strings.EqualFold(check.GetName(), idOrName) {
+			return check, true
+		}
+	}
+	return nil, false
+}
+
+/*
+ * Returns trigger of check only if its monitor has finished the first loop:
+ * callbacks of triggers are set up when monitor starts, so values can't be fed before.
+ */
+func ReadyTrigger(lastLoop time.Time, trigger *Trigger) *Trigger {
+	if lastLoop.IsZero() {
+		return nil
+	}
+	return trigger
+}
+
+/*
+ * Executes check out-of-band and describes which state value matches.
+ * Value is fed into trigger only if feed == true and monitor is started.
+ */
+func RunCheck(check Check, feed bool) (*RunResult, error) {
+	trigger := check.GetTrigger()
+
+	value, err := check.Run(feed && trigger != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RunResult{
+		ReportId: check.GetReportId(),
+		Name:     check.GetName(),
+		Monitor:  check.GetMonitor(),
+		Value:    value,
+		Fed:      feed && trigger != nil,
+	}
+
+	if trigger != nil {
+		result.Match = trigger.Match(value)
+		result.State = trigger.GetState()
+	}
+
+	return result, nil
+}
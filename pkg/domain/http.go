@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DTO for http listener configuration
+type HttpOptions struct {
+	Listen   string
+	TlsCert  string
+	TlsKey   string
+	BasePath string // prefix for all callback urls (e.g. "/fuse" behind reverse proxy)
+	ApiToken string // bearer token for http api (api is disabled if empty)
+}
+
+func DefaultHttpOptions() HttpOptions {
+	return HttpOptions{
+		Listen:   ":7777",
+		TlsCert:  "",
+		TlsKey:   "",
+		BasePath: "",
+		ApiToken: "",
+	}
+}
+
+/*
+ * Returns normalized base path: without trailing slash and always with leading one
+ * (empty string for root).
+ */
+func (o HttpOptions) GetBasePath() string {
+	path := strings.Trim(o.BasePath, "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+/*
+ * Creates listener and starts serving handler in background.
+ * Returns error if listener can't be created.
+ */
+func (o HttpOptions) serve(handler http.Handler) (*http.Server, error) {
+	if (o.TlsCert == "") != (o.TlsKey == "") {
+		return nil, fmt.Errorf("both 'tls_cert' and 'tls_key' options are required for https")
+	}
+
+	ln, err := net.Listen("tcp", o.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{Handler: handler}
+
+	go func() {
+		var err error
+		if o.TlsCert != "" {
+			err = server.ServeTLS(ln, o.TlsCert, o.TlsKey)
+		} else {
+			err = server.Serve(ln)
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("notifer: http listener stopped")
+		}
+	}()
+
+	return server, nil
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type httpAlerter struct {
+	Alerter // only ConfigureHTTP is used in tests
+
+	handler http.HandlerFunc
+}
+
+func (a *httpAlerter) ConfigureHTTP(mux *http.ServeMux, basePath string) {
+	mux.HandleFunc(basePath+"/slow", a.handler)
+}
+
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestHttpOptionsBasePath(t *testing.T) {
+	for path, expected := range map[string]string{"": "", "/": "", "fuse": "/fuse", "/fuse/": "/fuse"} {
+		assert.Equal(t, expected, HttpOptions{BasePath: path}.GetBasePath())
+	}
+}
+
+func TestNotiferShutdownDrainsCallbacks(t *testing.T) {
+	started := make(chan bool)
+	alerter := &httpAlerter{handler: func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	}}
+
+	n := NewNotifer()
+	n.Http.Listen = freeAddress(t)
+	n.Http.BasePath = "/fuse/"
+	n.AddAlerter("slow", alerter)
+	assert.NoError(t, n.Start())
+
+	body := make(chan string)
+	go func() {
+		res, err := http.Get("http://" + n.Http.Listen + "/fuse/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		bytes, _ := ioutil.ReadAll(res.Body)
+		body <- string(bytes)
+	}()
+
+	<-started
+	assert.NoError(t, n.Shutdown(context.Background()))
+	assert.Equal(t, "done", <-body, "in-flight callback must be finished before shutdown")
+}
+
+func TestNotiferStartFailsOnBusyAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	n := NewNotifer()
+	n.Http.Listen = ln.Addr().String()
+	assert.Error(t, n.Start())
+}
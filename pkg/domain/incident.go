@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*
+ * Incident is an active (not yet resolved) report shared between all alerters.
+ * It is safe to use from monitor's gorutines and http callbacks at the same time.
+ */
+type Incident struct {
+	Id    string    // report id of check
+	Since time.Time // time of first report
+
+	mu            sync.RWMutex
+	message       Message           // last reported message
+	ackedBy       string            // who acknowledged incident (empty if not acknowledged)
+	silencedUntil time.Time         // bad notifications are suppressed until this time
+	events        []IncidentEvent   // history of actions with incident
+	attrs         map[string]string // alerter's data bound to incident (e.g. id of slack message)
+}
+
+type IncidentEvent struct {
+	Time time.Time
+	Text string
+}
+
+/*
+ * Thread-safe registry of active incidents.
+ */
+type Incidents struct {
+	mu    sync.RWMutex
+	items map[string]*Incident
+}
+
+func NewIncidents() *Incidents {
+	return &Incidents{
+		items: make(map[string]*Incident),
+	}
+}
+
+/*
+ * Creates new incident or updates message of existing one.
+ */
+func (s *Incidents) Open(id string, msg Message) *Incident {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, ok := s.items[id]
+	if !ok {
+		incident = &Incident{
+			Id:    id,
+			Since: time.Now(),
+			attrs: make(map[string]string),
+		}
+		s.items[id] = incident
+	}
+
+	incident.setMessage(msg)
+	return incident
+}
+
+/*
+ * Removes incident from registry.
+ * Returns nil if there was no incident with such id.
+ */
+func (s *Incidents) Close(id string) *Incident {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, ok := s.items[id]
+	if !ok {
+		return nil
+	}
+
+	delete(s.items, id)
+	return incident
+}
+
+func (s *Incidents) Get(id string) (*Incident, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	incident, ok := s.items[id]
+	return incident, ok
+}
+
+func (i *Incident) GetMessage() Message {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.message
+}
+
+func (i *Incident) GetDuration() time.Duration {
+	return time.Since(i.Since)
+}
+
+func (i *Incident) setMessage(msg Message) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.message = msg
+}
+
+/*
+ * Marks incident as acknowledged.
+ * Returns false if incident was already acknowledged.
+ */
+func (i *Incident) Ack(by string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.ackedBy != "" {
+		return false
+	}
+
+	i.ackedBy = by
+	i.events = append(i.events, IncidentEvent{time.Now(), "acknowledged by " + by})
+	return true
+}
+
+func (i *Incident) IsAcked() bool {
+	return i.GetAckedBy() != ""
+}
+
+func (i *Incident) GetAckedBy() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.ackedBy
+}
+
+/*
+ * Suppresses bad notifications of incident until provided time.
+ */
+func (i *Incident) Silence(until time.Time, by string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.silencedUntil = until
+	i.events = append(i.events, IncidentEvent{time.Now(), fmt.Sprintf("silenced until %s by %s", until.Format("15:04"), by)})
+}
+
+func (i *Incident) IsSilenced(at time.Time) bool {
+	return at.Before(i.GetSilencedUntil())
+}
+
+func (i *Incident) GetSilencedUntil() time.Time {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.silencedUntil
+}
+
+func (i *Incident) AddEvent(format string, args ...interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.events = append(i.events, IncidentEvent{time.Now(), fmt.Sprintf(format, args...)})
+}
+
+func (i *Incident) GetEvents() []IncidentEvent {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	events := make([]IncidentEvent, len(i.events))
+	copy(events, i.events)
+	return events
+}
+
+func (i *Incident) SetAttr(key, value string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.attrs[key] = value
+}
+
+func (i *Incident) GetAttr(key string) string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.attrs[key]
+}
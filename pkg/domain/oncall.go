@@ -0,0 +1,324 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ROTATION_DAILY  = "daily"
+	ROTATION_WEEKLY = "weekly"
+
+	ONCALL_PREFIX = "oncall:" // alerter's target "oncall:primary" means current member of "primary" schedule
+)
+
+/*
+ * On-call rotation: members take duty one by one, changing at handoff time.
+ */
+type Schedule struct {
+	Name     string
+	Rotation string         // ROTATION_DAILY or ROTATION_WEEKLY
+	Members  []string       // contacts (phones, slack ids, ...) in order of duty
+	Weekday  time.Weekday   // day of handoff for weekly rotation
+	Hour     int            // time of handoff
+	Minute   int            // ...
+	Location *time.Location // timezone for handoff and overrides
+	Start    time.Time      // first member takes duty at first handoff after this date
+
+	mu        sync.RWMutex
+	overrides []Override
+}
+
+/*
+ * Temporary replacement of on-call member.
+ */
+type Override struct {
+	Member string
+	From   time.Time
+	To     time.Time
+}
+
+/*
+ * Thread-safe registry of schedules by name.
+ */
+type Schedules struct {
+	mu    sync.RWMutex
+	items map[string]*Schedule
+}
+
+func NewSchedule(name string) *Schedule {
+	return &Schedule{
+		Name:     name,
+		Rotation: ROTATION_WEEKLY,
+		Members:  make([]string, 0),
+		Weekday:  time.Monday,
+		Hour:     10,
+		Location: time.UTC,
+		Start:    time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+/*
+ * Parses handoff in format "10:00" (daily) or "mon 10:00" (weekly).
+ */
+func (s *Schedule) SetHandoff(handoff string) error {
+	parts := strings.Fields(handoff)
+	if len(parts) == 2 {
+		weekday, ok := weekdays[strings.ToLower(parts[0])]
+		if !ok {
+			return fmt.Errorf("wrong weekday '%s'", parts[0])
+		}
+		s.Weekday = weekday
+		parts = parts[1:]
+	}
+
+	if len(parts) != 1 {
+		return fmt.Errorf("wrong handoff format '%s', expected 'mon 10:00' or '10:00'", handoff)
+	}
+
+	t, err := time.Parse("15:04", parts[0])
+	if err != nil {
+		return err
+	}
+
+	s.Hour, s.Minute = t.Hour(), t.Minute()
+	return nil
+}
+
+/*
+ * Parses time in schedule's timezone (format "2006-01-02 15:04" or "2006-01-02").
+ */
+func (s *Schedule) ParseTime(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, s.Location)
+	if err != nil {
+		return time.ParseInLocation("2006-01-02", value, s.Location)
+	}
+	return t, nil
+}
+
+/*
+ * Checks that contact is one of members of rotation.
+ */
+func (s *Schedule) HasMember(member string) bool {
+	for _, m := range s.Members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) AddOverride(member string, from, to time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = append(s.overrides, Override{member, from, to})
+}
+
+/*
+ * Removes all overrides which are active at provided time.
+ */
+func (s *Schedule) ClearOverrides(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	overrides := make([]Override, 0, len(s.overrides))
+	for _, o := range s.overrides {
+		if !o.isActive(at) {
+			overrides = append(overrides, o)
+		}
+	}
+	s.overrides = overrides
+}
+
+/*
+ * Returns member who is on call at provided time (last added active override wins).
+ */
+func (s *Schedule) OnCall(at time.Time) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.overrides) - 1; i >= 0; i-- {
+		if s.overrides[i].isActive(at) {
+			return s.overrides[i].Member
+		}
+	}
+
+	return s.rotationMember(at)
+}
+
+/*
+ * Returns member by rotation (without overrides).
+ */
+func (s *Schedule) rotationMember(at time.Time) string {
+	if len(s.Members) == 0 {
+		return ""
+	}
+
+	n := len(s.Members)
+	index := s.shiftIndex(at) % n
+	if index < 0 {
+		index += n
+	}
+
+	return s.Members[index]
+}
+
+/*
+ * Returns time of next handoff after provided time.
+ */
+func (s *Schedule) NextHandoff(at time.Time) time.Time {
+	return s.addShift(s.lastHandoff(at), 1)
+}
+
+/*
+ * Number of shifts between first handoff and the one active at provided time.
+ */
+func (s *Schedule) shiftIndex(at time.Time) int {
+	first := s.firstHandoff()
+	days := daysBetween(first, s.lastHandoff(at))
+
+	if s.Rotation == ROTATION_DAILY {
+		return days
+	}
+	return days / 7
+}
+
+/*
+ * Finds the latest handoff moment which is not after provided time.
+ */
+func (s *Schedule) lastHandoff(at time.Time) time.Time {
+	local := at.In(s.Location)
+	handoff := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+
+	if s.Rotation == ROTATION_WEEKLY {
+		diff := (int(handoff.Weekday()) - int(s.Weekday) + 7) % 7
+		handoff = handoff.AddDate(0, 0, -diff)
+	}
+
+	if handoff.After(local) {
+		handoff = s.addShift(handoff, -1)
+	}
+
+	return handoff
+}
+
+/*
+ * Finds the first handoff moment on or after start date.
+ */
+func (s *Schedule) firstHandoff() time.Time {
+	start := s.Start.In(s.Location)
+	handoff := time.Date(start.Year(), start.Month(), start.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+
+	if s.Rotation == ROTATION_WEEKLY {
+		diff := (int(s.Weekday) - int(handoff.Weekday()) + 7) % 7
+		handoff = handoff.AddDate(0, 0, diff)
+	}
+
+	return handoff
+}
+
+func (s *Schedule) addShift(t time.Time, n int) time.Time {
+	if s.Rotation == ROTATION_DAILY {
+		return t.AddDate(0, 0, n)
+	}
+	return t.AddDate(0, 0, 7*n)
+}
+
+/*
+ * Returns active and future overrides.
+ */
+func (s *Schedule) GetOverrides(at time.Time) []Override {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	overrides := make([]Override, 0)
+	for _, o := range s.overrides {
+		if o.To.After(at) {
+			overrides = append(overrides, o)
+		}
+	}
+	return overrides
+}
+
+func (o Override) isActive(at time.Time) bool {
+	return !at.Before(o.From) && at.Before(o.To)
+}
+
+func NewSchedules() *Schedules {
+	return &Schedules{
+		items: make(map[string]*Schedule),
+	}
+}
+
+func (s *Schedules) Add(schedule *Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[schedule.Name] = schedule
+}
+
+func (s *Schedules) Get(name string) (*Schedule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.items[name]
+	return schedule, ok
+}
+
+/*
+ * Returns all schedules sorted by name.
+ */
+func (s *Schedules) List() []*Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Schedule, 0, len(s.items))
+	for _, schedule := range s.items {
+		list = append(list, schedule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+/*
+ * Resolves alerter's target: "oncall:{schedule}" is replaced by current on-call member,
+ * any other target is returned as is. Returns empty string for unknown schedule.
+ */
+func (s *Schedules) Resolve(target string) string {
+	if !strings.HasPrefix(target, ONCALL_PREFIX) {
+		return target
+	}
+
+	name := strings.TrimPrefix(target, ONCALL_PREFIX)
+	schedule, ok := s.Get(name)
+	if !ok {
+		log.WithField("schedule", name).Error("oncall: unknown schedule")
+		return ""
+	}
+
+	return schedule.OnCall(time.Now())
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+/*
+ * Number of calendar days between dates of two times (ignores DST shifts).
+ */
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSchedule(t *testing.T, rotation, handoff, timezone string) *Schedule {
+	location, err := time.LoadLocation(timezone)
+	assert.NoError(t, err)
+
+	s := NewSchedule("primary")
+	s.Rotation = rotation
+	s.Location = location
+	s.Members = []string{"alice", "bob", "carol"}
+	assert.NoError(t, s.SetHandoff(handoff))
+
+	s.Start, err = s.ParseTime("2026-10-01")
+	assert.NoError(t, err)
+	return s
+}
+
+func TestScheduleWeeklyRotation(t *testing.T) {
+	s := newTestSchedule(t, ROTATION_WEEKLY, "mon 10:00", "Europe/Moscow")
+
+	// first monday after start is 2026-10-05
+	matrix := map[string]string{
+		"2026-10-05 09:59": "carol", // before first handoff
+		"2026-10-05 10:00": "alice",
+		"2026-10-11 23:00": "alice",
+		"2026-10-12 09:00": "alice",
+		"2026-10-12 10:00": "bob",
+		"2026-10-19 10:30": "carol",
+		"2026-10-26 10:00": "alice",
+	}
+
+	for at, member := range matrix {
+		tm, err := s.ParseTime(at)
+		assert.NoError(t, err)
+		assert.Equalf(t, member, s.OnCall(tm), "wrong on-call member at %s", at)
+	}
+
+	// 10:00 in Moscow is 07:00 UTC
+	assert.Equal(t, "bob", s.OnCall(time.Date(2026, 10, 12, 7, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "alice", s.OnCall(time.Date(2026, 10, 12, 6, 59, 0, 0, time.UTC)))
+}
+
+func TestScheduleDailyRotationAcrossDST(t *testing.T) {
+	s := newTestSchedule(t, ROTATION_DAILY, "09:00", "Europe/Berlin")
+
+	// DST ends on 2026-10-25 in Berlin
+	matrix := map[string]string{
+		"2026-10-01 09:00": "alice",
+		"2026-10-24 09:00": "carol", // 23 days after start
+		"2026-10-25 08:59": "carol",
+		"2026-10-25 09:00": "alice",
+		"2026-10-26 09:00": "bob",
+	}
+
+	for at, member := range matrix {
+		tm, err := s.ParseTime(at)
+		assert.NoError(t, err)
+		assert.Equalf(t, member, s.OnCall(tm), "wrong on-call member at %s", at)
+	}
+
+	next := s.NextHandoff(time.Date(2026, 10, 25, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 10, 26, 8, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestScheduleOverrides(t *testing.T) {
+	s := newTestSchedule(t, ROTATION_WEEKLY, "mon 10:00", "UTC")
+
+	from, _ := s.ParseTime("2026-10-06 00:00")
+	to, _ := s.ParseTime("2026-10-07 00:00")
+	s.AddOverride("dave", from, to)
+
+	at, _ := s.ParseTime("2026-10-06 12:00")
+	assert.Equal(t, "dave", s.OnCall(at))
+	assert.Equal(t, "alice", s.OnCall(to))
+
+	s.AddOverride("erin", at, at.Add(time.Hour))
+	assert.Equal(t, "erin", s.OnCall(at), "last override wins")
+
+	s.ClearOverrides(at)
+	assert.Equal(t, "alice", s.OnCall(at))
+}
+
+func TestSchedulesResolve(t *testing.T) {
+	s := newTestSchedule(t, ROTATION_WEEKLY, "mon 10:00", "UTC")
+	s.Members = []string{"+70000000001"}
+
+	schedules := NewSchedules()
+	schedules.Add(s)
+
+	assert.Equal(t, "+70000000001", schedules.Resolve("oncall:primary"))
+	assert.Equal(t, "+79999999999", schedules.Resolve("+79999999999"))
+	assert.Equal(t, "", schedules.Resolve("oncall:unknown"))
+}
+
+func TestScheduleHandoffFormat(t *testing.T) {
+	s := NewSchedule("test")
+	assert.NoError(t, s.SetHandoff("fri 18:30"))
+	assert.Equal(t, time.Friday, s.Weekday)
+	assert.Equal(t, 18, s.Hour)
+	assert.Equal(t, 30, s.Minute)
+
+	assert.Error(t, s.SetHandoff("friday 18:30"))
+	assert.Error(t, s.SetHandoff("18-30"))
+}
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+/*
+ * Per-level templates which alerters use to render text of message.
+ * Templates are executed with *Message as data (see text/template).
+ */
+type MessageTemplates struct {
+	templates map[int]*template.Template
+}
+
+/*
+ * Result of preview query (or any other tabular details of check).
+ */
+type Table struct {
+	Columns   []string
+	Rows      [][]string
+	Truncated bool   // not all rows are included
+	Error     string // error during querying data for table
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+/*
+ * Creates templates from default texts; panics on wrong text, so use it for built-in templates only.
+ */
+func NewMessageTemplates(good, warn, crit string) *MessageTemplates {
+	t := &MessageTemplates{
+		templates: make(map[int]*template.Template),
+	}
+
+	for level, text := range map[int]string{MSG_LVL_GOOD: good, MSG_LVL_WARN: warn, MSG_LVL_CRIT: crit} {
+		if err := t.Set(level, text); err != nil {
+			panic(err)
+		}
+	}
+
+	return t
+}
+
+/*
+ * Replaces template for level (MSG_LVL_GOOD, MSG_LVL_WARN or MSG_LVL_CRIT).
+ */
+func (t *MessageTemplates) Set(level int, text string) error {
+	name := (&Message{Level: level}).LevelToStr()
+
+	tpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	t.templates[level] = tpl
+	return nil
+}
+
+/*
+ * Renders message with template of its level (messages of unknown level are rendered as warnings).
+ */
+func (t *MessageTemplates) Render(msg Message) (string, error) {
+	tpl, ok := t.templates[msg.Level]
+	if !ok {
+		tpl = t.templates[MSG_LVL_WARN]
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, &msg); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+/*
+ * Plain text rendering: comma separated values, one row per line.
+ */
+func (t *Table) String() string {
+	if t.Error != "" {
+		return "error: " + t.Error
+	}
+
+	if len(t.Rows) == 0 {
+		return "<empty dataset>"
+	}
+
+	lines := []string{strings.Join(t.Columns, ", ")}
+	for _, row := range t.Rows {
+		lines = append(lines, strings.Join(row, ", "))
+	}
+
+	if t.Truncated {
+		lines = append(lines, "... too many lines in output ...")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+/*
+ * Helper for building table from query results.
+ */
+func (t *Table) AddRow(values []interface{}) {
+	row := make([]string, 0, len(values))
+	for _, value := range values {
+		row = append(row, fmt.Sprintf("%v", value))
+	}
+	t.Rows = append(t.Rows, row)
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTemplatesPerLevel(t *testing.T) {
+	templates := NewMessageTemplates("ok: {{.Subject}}", "{{.LevelToStr | upper}}: {{.Subject}} {{.Duration}}", "down: {{.Subject}}")
+
+	msg := Message{Level: MSG_LVL_GOOD, Subject: "grafana", Duration: 90e9}
+	text, err := templates.Render(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok: grafana", text)
+
+	msg.Level = MSG_LVL_WARN
+	text, _ = templates.Render(msg)
+	assert.Equal(t, "WARN: grafana 1m30s", text)
+
+	msg.Level = MSG_LVL_UNKN
+	text, _ = templates.Render(msg)
+	assert.Equal(t, "UNKNOWN: grafana 1m30s", text, "unknown level is rendered as warning")
+
+	assert.NoError(t, templates.Set(MSG_LVL_CRIT, "{{.Title}}!"))
+	msg.Level, msg.Title = MSG_LVL_CRIT, "title"
+	text, _ = templates.Render(msg)
+	assert.Equal(t, "title!", text)
+
+	assert.Error(t, templates.Set(MSG_LVL_CRIT, "{{.Title"))
+
+	templates.Set(MSG_LVL_GOOD, "{{.Missing}}")
+	_, err = templates.Render(Message{Level: MSG_LVL_GOOD})
+	assert.Error(t, err)
+}
+
+func TestTableString(t *testing.T) {
+	table := &Table{Columns: []string{"time", "id"}}
+	assert.Equal(t, "<empty dataset>", table.String())
+
+	table.AddRow([]interface{}{"2026-10-19", 42})
+	table.Truncated = true
+	assert.Equal(t, "time, id\n2026-10-19, 42\n... too many lines in output ...", table.String())
+
+	table.Error = "timeout"
+	assert.Equal(t, "error: timeout", table.String())
+}
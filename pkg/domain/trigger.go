@@ -2,18 +2,47 @@ package domain
 
 import (
 	"fmt"
+	"sync"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 )
 
 const STATE_CRIT = "crit"
 
+const MAX_TRANSITIONS = 20 // how many last transitions are kept in trigger's history
+
 type Trigger struct {
-	state  *State   // current active state
-	states []*State // set of states to check
+	mu     sync.Mutex // trigger can be touched from monitor's loop and out-of-band runs
+	state  *State     // current active state
+	states []*State   // set of states to check
+
+	lastValue   interface{}  // value of last Touch or Fail
+	lastTouch   time.Time    // time of last Touch or Fail
+	transitions []Transition // last changes of active state
 
 	Callback func(state *State, lastValue interface{}) error // callback to call after changing the state
 }
 
+/*
+ * Change of trigger's active state.
+ */
+type Transition struct {
+	Time  time.Time
+	From  string
+	To    string
+	Value interface{}
+}
+
+/*
+ * Snapshot of state's counter for inspection.
+ */
+type StateCounter struct {
+	Name    string
+	Counter int
+	Cycles  int
+}
+
 type State struct {
 	Name   string // name of state
 	Cycles int    // if counter > Cycles then state considered to be active
@@ -43,6 +72,27 @@ func (t *Trigger) AddState(state *State) {
 	}
 }
 
+/*
+ * Returns new trigger with the same states (counters, history and callback are not copied).
+ * Used for triggers which are created on the fly, e.g. per series of query result.
+ */
+func (t *Trigger) Clone() *Trigger {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clone := NewTrigger(nil)
+	for _, state := range t.states {
+		clone.AddState(&State{
+			Name:     state.Name,
+			Cycles:   state.Cycles,
+			Value:    state.Value,
+			Operator: state.Operator,
+			AllowNil: state.AllowNil,
+		})
+	}
+	return clone
+}
+
 /*
  * Checks that only one state of trigger has AllowNil == true.
  * If no states has AllowNil flag set then if try to set it up for any "crit" state.
@@ -77,7 +127,11 @@ func (t *Trigger) SetupNilStates() {
  * Type of "value" must be string or float64 (it doesn't convert int to float).
  */
 func (t *Trigger) Touch(value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	newState := t.state
+	t.lastValue, t.lastTouch = value, time.Now()
 
 	log.WithFields(log.Fields{"value": value}).Debug("trigger: comparing with value")
 	for _, state := range t.states {
@@ -102,7 +156,11 @@ func (t *Trigger) Touch(value interface{}) {
  * Fail function immediately switch trigger to STATE_CRIT state
  */
 func (t *Trigger) Fail(value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	log.WithField("value", value).Debug("trigger: failing trigger with value")
+	t.lastValue, t.lastTouch = value, time.Now()
 
 	var critState *State
 	for _, state := range t.states {
@@ -133,6 +191,7 @@ func (t *Trigger) activateState(state *State, value interface{}) {
 		return // nothing to trigger
 	}
 
+	t.addTransition(t.state, state, value)
 	t.state = state
 	log.WithFields(log.Fields{"state": state.Name}).Debug("trigger: activating new state")
 
@@ -150,6 +209,83 @@ func (t *Trigger) activateState(state *State, value interface{}) {
 	}
 }
 
+func (t *Trigger) addTransition(from, to *State, value interface{}) {
+	transition := Transition{Time: time.Now(), To: to.Name, Value: value}
+	if from != nil {
+		transition.From = from.Name
+	}
+
+	t.transitions = append(t.transitions, transition)
+	if len(t.transitions) > MAX_TRANSITIONS {
+		t.transitions = t.transitions[len(t.transitions)-MAX_TRANSITIONS:]
+	}
+}
+
+/*
+ * Returns name of state which value matches (the last one in check order as in Touch)
+ * without changing counters. Returns empty string if value matches no state.
+ */
+func (t *Trigger) Match(value interface{}) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match := ""
+	for _, state := range t.states {
+		if state.test(value) {
+			match = state.Name
+		}
+	}
+	return match
+}
+
+/*
+ * Returns name of active state.
+ */
+func (t *Trigger) GetState() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == nil {
+		return ""
+	}
+	return t.state.Name
+}
+
+/*
+ * Returns counters of all states in check order.
+ */
+func (t *Trigger) GetCounters() []StateCounter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counters := make([]StateCounter, 0, len(t.states))
+	for _, state := range t.states {
+		counters = append(counters, StateCounter{state.Name, state.counter, state.Cycles})
+	}
+	return counters
+}
+
+/*
+ * Returns last value and time when it was received (zero time if trigger was never touched).
+ */
+func (t *Trigger) GetLastValue() (interface{}, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastValue, t.lastTouch
+}
+
+/*
+ * Returns last transitions (oldest first).
+ */
+func (t *Trigger) GetTransitions() []Transition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	transitions := make([]Transition, len(t.transitions))
+	copy(transitions, t.transitions)
+	return transitions
+}
+
 func (t *Trigger) LogStates() {
 	msg := ""
 	for _, state := range t.states {
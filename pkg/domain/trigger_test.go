@@ -310,3 +310,55 @@ func TestTriggerFail(t *testing.T) {
 	assert.Equal(t, "good", trigger.state.Name, "Trigger's state must be 'good'")
 	assert.Equal(t, 2, callCnt, "callback must be trigger only twice (fail + good)")
 }
+
+func TestTriggerKeepsTransitions(t *testing.T) {
+	trig := NewTrigger(func(*State, interface{}) error { return nil })
+	trig.AddState(&State{Name: "good", Cycles: 1, Value: float64(0), Operator: "="})
+	trig.AddState(&State{Name: "crit", Cycles: 2, Value: float64(0), Operator: ">"})
+
+	trig.Touch(float64(5))
+	assert.Equal(t, "good", trig.GetState())
+	assert.Equal(t, []StateCounter{{"good", 0, 1}, {"crit", 1, 2}}, trig.GetCounters())
+
+	trig.Touch(float64(7))
+	trig.Touch(float64(0))
+
+	value, at := trig.GetLastValue()
+	assert.Equal(t, float64(0), value)
+	assert.False(t, at.IsZero())
+
+	transitions := trig.GetTransitions()
+	assert.Len(t, transitions, 2)
+	assert.Equal(t, "good", transitions[0].From)
+	assert.Equal(t, "crit", transitions[0].To)
+	assert.Equal(t, float64(7), transitions[0].Value)
+	assert.Equal(t, "good", transitions[1].To)
+
+	for i := 0; i < MAX_TRANSITIONS; i++ {
+		trig.Fail("error")
+		trig.Touch(float64(0))
+	}
+	assert.Len(t, trig.GetTransitions(), MAX_TRANSITIONS)
+}
+
+func TestTriggerClone(t *testing.T) {
+	trig := NewTrigger(func(*State, interface{}) error { return nil })
+	trig.AddState(&State{Name: "good", Cycles: 1, Value: float64(0), Operator: "="})
+	trig.AddState(&State{Name: "crit", Cycles: 1, Value: float64(0), Operator: ">", AllowNil: true})
+
+	trig.Touch(float64(5))
+	assert.Equal(t, "crit", trig.GetState())
+
+	clone := trig.Clone()
+	assert.Nil(t, clone.Callback)
+	assert.Equal(t, "good", clone.GetState(), "clone starts from first state")
+	assert.Len(t, clone.GetTransitions(), 0)
+	assert.Equal(t, []StateCounter{{"good", 0, 1}, {"crit", 0, 1}}, clone.GetCounters())
+	assert.Equal(t, "crit", clone.Match(nil))
+
+	// states are not shared
+	clone.Callback = func(*State, interface{}) error { return nil }
+	clone.Touch(float64(0))
+	assert.Equal(t, []StateCounter{{"good", 1, 1}, {"crit", 0, 1}}, clone.GetCounters())
+	assert.Equal(t, "crit", trig.GetState())
+}
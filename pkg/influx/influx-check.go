@@ -0,0 +1,108 @@
+package influx
+
+import (
+	"fmt"
+
+	"fuse/pkg/domain"
+)
+
+/*
+ * Exposes influx check as check for chat-ops.
+ */
+type influxCheck struct {
+	influx *Influx
+	check  *Check
+}
+
+/*
+ * CheckSource interface implementation.
+ */
+func (i *Influx) GetChecks() []domain.Check {
+	checks := make([]domain.Check, 0, len(i.checks))
+	for _, check := range i.checks {
+		if !check.PerSeries {
+			checks = append(checks, &influxCheck{i, check})
+			continue
+		}
+
+		// trigger of check is only a prototype, so series are listed instead
+		for _, series := range check.GetSeries() {
+			checks = append(checks, &seriesCheck{influxCheck{i, check}, series})
+		}
+	}
+	return checks
+}
+
+func (c *influxCheck) GetReportId() string {
+	return c.check.GetReportId()
+}
+
+func (c *influxCheck) GetName() string {
+	return c.check.Info
+}
+
+func (c *influxCheck) GetMonitor() string {
+	return c.influx.GetName()
+}
+
+func (c *influxCheck) GetQuery() string {
+	return c.influx.getSqlForCheck(c.check)
+}
+
+func (c *influxCheck) GetTrigger() *domain.Trigger {
+	return domain.ReadyTrigger(c.influx.GetLastLoop(), c.check.Trigger)
+}
+
+func (c *influxCheck) Run(feed bool) (interface{}, error) {
+	value, err := c.influx.queryCheck(c.check, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if trigger := c.GetTrigger(); feed && trigger != nil {
+		trigger.Touch(value)
+	}
+	return value, nil
+}
+
+func (c *influxCheck) GetPreview() *domain.Table {
+	return c.influx.getPreview(c.check)
+}
+
+/*
+ * Exposes one series of check in "per series" mode.
+ */
+type seriesCheck struct {
+	influxCheck
+	series *Series
+}
+
+func (c *seriesCheck) GetReportId() string {
+	return c.series.GetReportId()
+}
+
+func (c *seriesCheck) GetName() string {
+	return c.check.Info + " " + c.series.String()
+}
+
+func (c *seriesCheck) GetTrigger() *domain.Trigger {
+	return c.series.Trigger
+}
+
+func (c *seriesCheck) Run(feed bool) (interface{}, error) {
+	values, err := c.influx.querySeriesOfCheck(c.check, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		if SeriesKey(value.Tags) == c.series.Key {
+			if trigger := c.GetTrigger(); feed && trigger != nil {
+				trigger.Touch(value.Value)
+			}
+			return value.Value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("series %s is missing in query result", c.series)
+}
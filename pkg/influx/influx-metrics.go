@@ -12,6 +12,7 @@ import (
 type InfluxMetrics struct {
 	client   influx.Client // influx api client
 	bpConfig influx.BatchPointsConfig
+	flux     *fluxClient // influx 2.x api client, used instead of client if set
 }
 
 func NewInfluxMetrics(opts InfluxOptions) *InfluxMetrics {
@@ -35,12 +36,16 @@ func NewInfluxMetrics(opts InfluxOptions) *InfluxMetrics {
 	}
 }
 
-func (i *InfluxMetrics) Save(msg domain.Message) error {
-	bp, err := influx.NewBatchPoints(i.bpConfig)
-	if err != nil {
-		return err
+/*
+ * Writes metrics to bucket of influx 2.x.
+ */
+func NewInflux2Metrics(opts Influx2Options) *InfluxMetrics {
+	return &InfluxMetrics{
+		flux: &fluxClient{opts},
 	}
+}
 
+func (i *InfluxMetrics) Save(msg domain.Message) error {
 	tags, values := i.prepareTagValues(msg)
 
 	log.WithField("tags", tags).
@@ -53,6 +58,16 @@ func (i *InfluxMetrics) Save(msg domain.Message) error {
 	if err != nil {
 		return err
 	}
+
+	if i.flux != nil {
+		return i.flux.write(p)
+	}
+
+	bp, err := influx.NewBatchPoints(i.bpConfig)
+	if err != nil {
+		return err
+	}
+
 	bp.AddPoint(p)
 	return i.client.Write(bp)
 }
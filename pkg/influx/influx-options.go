@@ -18,3 +18,24 @@ func DefaultInfluxOptions() InfluxOptions {
 		Alert:           "",
 	}
 }
+
+// DTO for influx 2.x configuration
+type Influx2Options struct {
+	Address  string
+	Org      string
+	Token    string
+	Bucket   string // bucket for metrics, also available as %bucket in templates
+	Interval int
+	Alert    string
+}
+
+func DefaultInflux2Options() Influx2Options {
+	return Influx2Options{
+		Address:  "http://localhost:8086",
+		Org:      "",
+		Token:    "",
+		Bucket:   "telegraf",
+		Interval: 5,
+		Alert:    "",
+	}
+}
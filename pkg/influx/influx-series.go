@@ -0,0 +1,212 @@
+package influx
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"fuse/pkg/domain"
+)
+
+const SERIES_STALE_LOOPS = 3 // series is removed after this amount of loops without it in query result
+
+/*
+ * One series of GROUP BY query (e.g. one host) of check with "per series" mode.
+ */
+type Series struct {
+	Key     string            // tags sorted by name: "host=web1,region=eu"
+	Tags    map[string]string // tags of series from query result
+	Trigger *domain.Trigger   // copy of check's trigger
+
+	check  *Check
+	missed int // amount of consecutive loops without series in query result
+}
+
+/*
+ * Value of first column of series in query result.
+ */
+type seriesValue struct {
+	Tags  map[string]string
+	Value interface{}
+}
+
+func newSeries(check *Check, tags map[string]string) *Series {
+	return &Series{
+		Key:     SeriesKey(tags),
+		Tags:    tags,
+		Trigger: check.Trigger.Clone(),
+		check:   check,
+	}
+}
+
+/*
+ * Key of series by its tags: "host=web1,region=eu".
+ */
+func SeriesKey(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for tag, value := range tags {
+		pairs = append(pairs, tag+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (s *Series) GetReportId() string {
+	h := md5.New()
+	io.WriteString(h, s.check.GetReportId()+"|"+s.Key)
+	return fmt.Sprintf("%.5x", h.Sum(nil))
+}
+
+func (s *Series) String() string {
+	return "[" + s.Key + "]"
+}
+
+/*
+ * Returns series of check sorted by key.
+ */
+func (c *Check) GetSeries() []*Series {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	series := make([]*Series, 0, len(c.series))
+	for _, s := range c.series {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Key < series[j].Key })
+	return series
+}
+
+/*
+ * Executes query and returns column of first row of every series.
+ */
+func (i *Influx) querySeries(sql, column string, cache queryCache) ([]seriesValue, error) {
+	res, err := i.queryCached(sql, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]seriesValue, 0, len(res.Series))
+	for _, row := range res.Series {
+		value, err := columnValue(row, column)
+		if err != nil {
+			return nil, err
+		}
+
+		tags := make(map[string]string, len(row.Tags))
+		for tag, v := range row.Tags {
+			tags[tag] = v
+		}
+
+		values = append(values, seriesValue{tags, value})
+	}
+
+	return values, nil
+}
+
+/*
+ * Renders and executes query of check in "per series" mode.
+ */
+func (i *Influx) querySeriesOfCheck(check *Check, cache queryCache) ([]seriesValue, error) {
+	sql := i.getSqlForCheck(check)
+	log.WithFields(log.Fields{"sql": strings.TrimSpace(sql)}).Debug("influx: executing sql")
+
+	return i.querySeries(sql, check.Column, cache)
+}
+
+/*
+ * Sends values to triggers of series (new series get their own trigger)
+ * and removes series which are missing in results for SERIES_STALE_LOOPS loops.
+ * Empty result is an outage of data rather than disappearance of every series,
+ * so all known series get nil value (see AllowNil of trigger) and aren't removed.
+ */
+func (i *Influx) touchSeries(check *Check, values []seriesValue) {
+	touched := make(map[*Series]interface{}, len(values))
+	stale := make([]*Series, 0)
+
+	check.mu.Lock()
+	if check.series == nil {
+		check.series = make(map[string]*Series)
+	}
+
+	for _, value := range values {
+		key := SeriesKey(value.Tags)
+
+		series, ok := check.series[key]
+		if !ok {
+			series = newSeries(check, value.Tags)
+			series.Trigger.Callback = i.makeCallback(check, series)
+			check.series[key] = series
+
+			log.WithFields(log.Fields{"info": check.Info, "series": key}).Info("influx: new series")
+		}
+
+		series.missed = 0
+		touched[series] = value.Value
+	}
+
+	for key, series := range check.series {
+		if len(values) == 0 {
+			touched[series] = nil
+			continue
+		}
+
+		if _, ok := touched[series]; ok {
+			continue
+		}
+
+		series.missed++
+		if series.missed >= SERIES_STALE_LOOPS {
+			delete(check.series, key)
+			stale = append(stale, series)
+		}
+	}
+	check.mu.Unlock()
+
+	// triggers call alerters, so don't hold the lock
+	for series, value := range touched {
+		log.WithFields(log.Fields{"series": series.Key, "value": value}).Debug("influx: sending value to trigger of series")
+		series.Trigger.Touch(value)
+	}
+
+	for _, series := range stale {
+		i.removeSeries(check, series)
+	}
+}
+
+/*
+ * Resolves incident of series which has disappeared from query result.
+ */
+func (i *Influx) removeSeries(check *Check, series *Series) {
+	log.WithFields(log.Fields{"info": check.Info, "series": series.Key}).Info("influx: removing stale series")
+
+	reportId := series.GetReportId()
+	if _, ok := i.notifer.Incidents.Get(reportId); !ok {
+		return // nothing was reported
+	}
+
+	args := i.getArgsForCheck(check)
+	for tag, value := range series.Tags {
+		args[tag] = value
+	}
+
+	info := check.Info + " " + series.String()
+	msg := domain.Message{
+		Level:    domain.MSG_LVL_GOOD,
+		IconUrl:  i.icon,
+		From:     i.name,
+		ReportId: reportId,
+		Title:    fmt.Sprintf("QUERY: %s is gone", info),
+		Body:     fmt.Sprintf("Series is missing in query result for %d checks, incident is resolved.", SERIES_STALE_LOOPS),
+		Subject:  info,
+		Query:    i.getSqlForCheck(check),
+		Details:  map[string]string{"template": check.Template, "series": series.Key},
+		Args:     args,
+	}
+
+	i.notifer.Notify("good", i.options.Alert, msg)
+	i.notifer.Resolve(reportId)
+}
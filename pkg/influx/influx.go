@@ -5,17 +5,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/models"
 	log "github.com/sirupsen/logrus"
 
 	"fuse/pkg/domain"
+	"fuse/pkg/monitor"
 )
 
 type Influx struct {
-	client  client.Client   // influx api client
+	monitor.Pulse
+
+	name    string          // name of monitor: "influx", "influx2" or name of other query monitor
+	icon    string          // icon of alert messages
+	backend QueryBackend    // executes queries of templates
 	notifer *domain.Notifer // notifer to send alters to
 
 	options   InfluxOptions
@@ -23,6 +31,24 @@ type Influx struct {
 	checks    []*Check
 }
 
+const INFLUX_ICON = "https://aperogeek.fr/wp-content/uploads/2017/04/influx_logo.png" // TODO: replace
+
+/*
+ * Executes query and returns its first result (InfluxQL for 1.x, Flux for 2.x, etc).
+ * Every series of result is a separate group of values (e.g. host) with its tags.
+ */
+type QueryBackend interface {
+	Query(query string) (*client.Result, error)
+}
+
+/*
+ * InfluxQL queries via 1.x api.
+ */
+type influxQL struct {
+	client   client.Client // influx api client
+	database string
+}
+
 type Template struct {
 	Name    string
 	Body    string
@@ -31,16 +57,24 @@ type Template struct {
 }
 
 type Check struct {
-	Template string // name of template
-	Info     string // info string for alert message
-	Values   []string
-	Trigger  *domain.Trigger
+	Template  string // name of template
+	Info      string // info string for alert message
+	Values    []string
+	Trigger   *domain.Trigger
+	PerSeries bool   // every series of GROUP BY query is checked with its own copy of Trigger
+	Column    string // column to check (first column after time if empty)
+
+	mu     sync.Mutex         // series are updated by monitor's loop and read by chat-ops
+	series map[string]*Series // series of last results by key of tags
 }
 
 // TODO: add constructor and save calculation into cache-field
 func (c *Check) GetReportId() string {
 	h := md5.New()
 	io.WriteString(h, c.Template+"|"+c.Info+"|"+strings.Join(c.Values, "|"))
+	if c.Column != "" {
+		io.WriteString(h, "|"+c.Column)
+	}
 	return fmt.Sprintf("%.5x", h.Sum(nil))
 }
 
@@ -53,8 +87,18 @@ func NewInflux(options InfluxOptions) *Influx {
 		log.Fatalln("influx: ", err)
 	}
 
+	return NewQueryMonitor("influx", INFLUX_ICON, &influxQL{c, options.Database}, options)
+}
+
+/*
+ * Creates monitor which checks results of templates executed by backend.
+ * Only Interval and Alert of options are used.
+ */
+func NewQueryMonitor(name, icon string, backend QueryBackend, options InfluxOptions) *Influx {
 	return &Influx{
-		client:    c,
+		name:      name,
+		icon:      icon,
+		backend:   backend,
 		options:   options,
 		templates: make(map[string]*Template),
 		checks:    make([]*Check, 0),
@@ -70,30 +114,59 @@ func (i *Influx) AddCheck(check *Check) {
 }
 
 /*
- * Executes query and returns first column as string or float.
- * Returns error if data can't be converted to string or float.
+ * Results of queries during one loop of monitor, so checks of
+ * different columns of the same query don't execute it again.
  */
-func (i *Influx) querySingleColumn(sql string) (interface{}, error) {
-	q := client.Query{
-		Command:  sql,
-		Database: i.options.Database,
+type queryCache map[string]*client.Result
+
+/*
+ * Executes query and returns column of first row as string or float (see columnValue).
+ * Cache can be nil.
+ */
+func (i *Influx) queryColumn(sql, column string, cache queryCache) (interface{}, error) {
+	res, err := i.queryCached(sql, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Series == nil {
+		return nil, nil
 	}
 
-	res, err := i.client.Query(q)
+	return columnValue(res.Series[0], column)
+}
 
-	if err != nil {
-		return 0, err
+/*
+ * Converts value of column (first column after time if column is empty) of first row to string or float.
+ * Returns error if there is no such column or data can't be converted to string or float.
+ */
+func columnValue(row models.Row, column string) (interface{}, error) {
+	index := 1
+	if len(row.Columns) > 0 && row.Columns[0] != "time" && row.Columns[0] != "_time" {
+		index = 0 // result without time, e.g. of sql query
 	}
 
-	if res.Error() != nil {
-		return 0, res.Error()
+	if column != "" {
+		index = -1
+		for n, name := range row.Columns {
+			if name == column {
+				index = n
+			}
+		}
+
+		if index < 0 {
+			return nil, fmt.Errorf("column \"%s\" is missing in result (columns: %s)", column, strings.Join(row.Columns, ", "))
+		}
 	}
 
-	if res.Results[0].Series == nil {
+	if len(row.Values) == 0 || len(row.Values[0]) <= index {
 		return nil, nil
 	}
 
-	value := res.Results[0].Series[0].Values[0][1]
+	value := row.Values[0][index]
+	if value == nil {
+		return nil, nil // NULL of sql query
+	}
 
 	if number, ok := value.(json.Number); ok {
 		return number.Float64()
@@ -106,16 +179,34 @@ func (i *Influx) querySingleColumn(sql string) (interface{}, error) {
 	return nil, fmt.Errorf("result is not nor json.Number nor string")
 }
 
+/*
+ * Executes query or takes its result from cache.
+ */
+func (i *Influx) queryCached(sql string, cache queryCache) (*client.Result, error) {
+	if res, ok := cache[sql]; ok {
+		return res, nil
+	}
+
+	res, err := i.queryMultipleColumns(sql)
+	if err == nil && cache != nil {
+		cache[sql] = res
+	}
+
+	return res, err
+}
+
 /*
  * Executes query and returns first influx result.
  */
 func (i *Influx) queryMultipleColumns(sql string) (*client.Result, error) {
-	q := client.Query{
-		Command:  sql,
-		Database: i.options.Database,
-	}
+	return i.backend.Query(sql)
+}
 
-	res, err := i.client.Query(q)
+func (q *influxQL) Query(sql string) (*client.Result, error) {
+	res, err := q.client.Query(client.Query{
+		Command:  sql,
+		Database: q.database,
+	})
 
 	if err != nil {
 		return nil, err
@@ -134,7 +225,7 @@ func (i *Influx) queryMultipleColumns(sql string) (*client.Result, error) {
  * Monitor interface implementation.
  */
 func (i *Influx) GetName() string {
-	return "influx"
+	return i.name
 }
 
 /*
@@ -143,19 +234,28 @@ func (i *Influx) GetName() string {
  */
 func (i *Influx) RunWith(notifer *domain.Notifer) {
 	i.notifer = notifer
-	i.setupTriggers(i.options.Interval)
+	i.setupTriggers()
 
 	for {
-		log.Info("influx: check loop...")
+		log.Infof("%s: check loop...", i.name)
+		cache := make(queryCache)
 
 		for _, check := range i.checks {
 
 			log.WithFields(log.Fields{"info": check.Info}).Debug("influx: next check")
 
-			sql := i.getSqlForCheck(check)
-			log.WithFields(log.Fields{"sql": strings.TrimSpace(sql)}).Debug("influx: executing sql")
+			if check.PerSeries {
+				values, err := i.querySeriesOfCheck(check, cache)
+				if err != nil {
+					log.Error("influx: error during query execution: ", err)
+					continue
+				}
+
+				i.touchSeries(check, values)
+				continue
+			}
 
-			value, err := i.querySingleColumn(sql)
+			value, err := i.queryCheck(check, cache)
 			if err != nil {
 				log.Error("influx: error during query execution: ", err)
 				continue
@@ -172,130 +272,174 @@ func (i *Influx) RunWith(notifer *domain.Notifer) {
 			check.Trigger.Touch(value)
 		}
 
+		i.Beat(time.Duration(i.options.Interval) * time.Second)
 		time.Sleep(time.Duration(i.options.Interval) * time.Second)
 	}
 }
 
 /*
- * Prepare trigger's callback for every check.
+ * Renders and executes query of check.
  */
-func (i *Influx) setupTriggers(interval int) {
-	channel := i.options.Alert
+func (i *Influx) queryCheck(check *Check, cache queryCache) (interface{}, error) {
+	sql := i.getSqlForCheck(check)
+	log.WithFields(log.Fields{"sql": strings.TrimSpace(sql)}).Debug("influx: executing sql")
+
+	return i.queryColumn(sql, check.Column, cache)
+}
 
+/*
+ * Prepare trigger's callback for every check.
+ */
+func (i *Influx) setupTriggers() {
 	for _, check := range i.checks {
 		check.Trigger.SetupNilStates()
+		check.Trigger.Callback = i.makeCallback(check, nil) // triggers of series are set up on the fly
+	}
+}
 
-		_check := check // catch var for closure
+/*
+ * Creates trigger's callback which sends alerts of check (or of one series of check).
+ */
+func (i *Influx) makeCallback(check *Check, series *Series) func(*domain.State, interface{}) error {
+	channel := i.options.Alert
+	interval := i.options.Interval
 
-		// assign new callback-closure
-		check.Trigger.Callback = func(state *domain.State, lastValue interface{}) error {
-			args := i.getArgsForCheck(_check)
+	reportId, info := check.GetReportId(), check.Info
+	if series != nil {
+		reportId, info = series.GetReportId(), check.Info+" "+series.String()
+	}
 
-			details := map[string]string{
-				"value":    fmt.Sprintf("%v", lastValue),
-				"template": _check.Template,
-			}
+	return func(state *domain.State, lastValue interface{}) error {
+		args := i.getArgsForCheck(check)
 
-			sql := i.getSqlForCheck(_check)
+		details := map[string]string{
+			"value":    fmt.Sprintf("%v", lastValue),
+			"template": check.Template,
+		}
 
-			var body string
-			switch state.Name {
-			case "good":
-				body = fmt.Sprintf("Query is good more than %d sec. ```%s```", interval*state.Cycles, sql)
-			case "warn":
-				body = fmt.Sprintf("*WARN:* query has bad value for more than %d sec. ```%s```", interval*state.Cycles, sql)
-			case "crit":
-				body = fmt.Sprintf("*CRITICAL:* query has bad value for more than %d sec. ```%s```", interval*state.Cycles, sql)
-			}
+		if check.Column != "" {
+			details["column"] = check.Column
+		}
 
-			msg := domain.Message{
-				IconUrl: "https://aperogeek.fr/wp-content/uploads/2017/04/influx_logo.png", // TODO: replace
-				From:    "influx",
-				Title:   fmt.Sprintf("QUERY: *%s* in %s state", _check.Info, strings.ToUpper(state.Name)),
-				Body:    body,
-				Details: details,
-				Args:    args,
+		if series != nil {
+			details["series"] = series.Key
+			for tag, value := range series.Tags {
+				args[tag] = value
 			}
+		}
 
-			msg.ParseLevel(state.Name)
+		duration := time.Duration(interval*state.Cycles) * time.Second
 
-			if msg.Level != domain.MSG_LVL_GOOD {
-				preview := i.getPreview(&msg, _check)
-				if preview == "" {
-					msg.Body += "\n`no preview query available`\n"
-				} else {
-					msg.Body += "\n*preview query:*\n" + preview
-				}
-			}
+		var body string
+		switch state.Name {
+		case "good":
+			body = fmt.Sprintf("Query is good more than %s.", duration)
+		default:
+			body = fmt.Sprintf("Query has bad value for more than %s.", duration)
+		}
 
-			switch msg.Level {
-			case domain.MSG_LVL_GOOD:
-				i.notifer.Resolve(_check.GetReportId())
-			default:
-				i.notifer.Report(_check.GetReportId(), msg)
-			}
+		msg := domain.Message{
+			IconUrl:  i.icon,
+			From:     i.name,
+			ReportId: reportId,
+			Title:    fmt.Sprintf("QUERY: %s in %s state", info, strings.ToUpper(state.Name)),
+			Body:     body,
+			Subject:  info,
+			Duration: duration,
+			Query:    i.getSqlForCheck(check),
+			Details:  details,
+			Args:     args,
+		}
+
+		msg.ParseLevel(state.Name)
+
+		if msg.Level != domain.MSG_LVL_GOOD {
+			msg.Preview = i.getPreview(check)
+		}
+
+		if msg.Level != domain.MSG_LVL_GOOD {
+			i.notifer.Report(reportId, msg)
+		}
 
-			i.notifer.Notify(state.Name, channel, msg)
+		i.notifer.Notify(state.Name, channel, msg)
 
-			return nil
+		// resolve after notification, so alerters can bind recovery message to incident
+		if msg.Level == domain.MSG_LVL_GOOD {
+			i.notifer.Resolve(reportId)
 		}
+
+		return nil
 	}
 }
 
 /*
- * Executes and returns formatted output of preview SQL query.
- * Returns empty string preview query was not provided in config file.
+ * Executes preview SQL query and returns its output as table.
+ * Returns nil if preview query was not provided in config file.
  * This method will retry 5 times before fail during influx preview querying.
  */
-func (i *Influx) getPreview(msg *domain.Message, check *Check) string {
+func (i *Influx) getPreview(check *Check) *domain.Table {
 	log.WithFields(log.Fields{"check": check.Info}).Info("influx: executing preview query")
 
-	var preview string
 	sql := i.getSqlPreviewForCheck(check)
 
 	if sql == "" {
-		return ""
+		return nil
 	}
 
 	for try := 0; ; try++ {
 		res, err := i.queryMultipleColumns(sql)
 
 		if err == nil {
-			if len(res.Series) == 0 {
-				return "```<empty dataset>```"
-			}
+			return previewTable(res)
+		}
 
-			ser := res.Series[0]
+		// TODO: config value for number of retries?
+		if try >= 5 {
+			log.WithFields(log.Fields{"check": check.Info}).Error("influx: executing preview query failed after 5 retries")
+			return &domain.Table{Error: fmt.Sprintf("Influx error: %s", err)}
+		}
+	}
+}
 
-			columns := strings.Join(ser.Columns, ", ")
-			table := make([]string, 0)
+/*
+ * Converts result of preview query to table. Rows of all series are
+ * included with tags of series as first columns if there are many series.
+ */
+func previewTable(res *client.Result) *domain.Table {
+	table := &domain.Table{}
+	if len(res.Series) == 0 {
+		return table
+	}
 
-			for nrow, row := range ser.Values {
-				if nrow > 5 {
-					table = append(table, "... too many lines in output ...")
-					break
-				}
+	tags := make([]string, 0)
+	if len(res.Series) > 1 {
+		for tag := range res.Series[0].Tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+	}
 
-				values := make([]string, 0)
-				for _, col := range row {
-					values = append(values, fmt.Sprintf("%s", col))
-				}
-				table = append(table, strings.Join(values, ", "))
+	table.Columns = append(tags, res.Series[0].Columns...)
+
+	nrow := 0
+	for _, ser := range res.Series {
+		for _, values := range ser.Values {
+			if nrow > 5 {
+				table.Truncated = true
+				return table
 			}
 
-			preview = fmt.Sprintf("```%s\n%s```", columns, strings.Join(table, "\n"))
-			break
-		}
+			row := make([]interface{}, 0, len(table.Columns))
+			for _, tag := range tags {
+				row = append(row, ser.Tags[tag])
+			}
 
-		// TODO: config value for number of retries?
-		if try >= 5 {
-			log.WithFields(log.Fields{"check": check.Info}).Error("influx: executing preview query failed after 5 retries")
-			preview = fmt.Sprintf("Influx error: %s", err)
-			break
+			table.AddRow(append(row, values...))
+			nrow++
 		}
 	}
 
-	return preview
+	return table
 }
 
 /*
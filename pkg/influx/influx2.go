@@ -0,0 +1,235 @@
+package influx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/models"
+	"github.com/parnurzeal/gorequest"
+)
+
+/*
+ * Flux queries and writes via 2.x api.
+ */
+type fluxClient struct {
+	options Influx2Options
+}
+
+/*
+ * Creates monitor which executes templates as Flux queries.
+ */
+func NewInflux2(options Influx2Options) *Influx {
+	return NewQueryMonitor("influx2", INFLUX_ICON, &fluxClient{options}, InfluxOptions{
+		Address:  options.Address,
+		Interval: options.Interval,
+		Alert:    options.Alert,
+	})
+}
+
+func (f *fluxClient) url(path string) string {
+	params := url.Values{"org": {f.options.Org}}
+	if path == "/api/v2/write" {
+		params.Set("bucket", f.options.Bucket)
+		params.Set("precision", "ns")
+	}
+
+	return strings.TrimRight(f.options.Address, "/") + path + "?" + params.Encode()
+}
+
+/*
+ * Executes Flux query and converts every table of annotated CSV answer to series:
+ * group key columns (except _start and _stop) become tags, "_time" is always the
+ * first column and "_value" is the second one if it exists, so checks without
+ * column take "_value" by default.
+ */
+func (f *fluxClient) Query(query string) (*client.Result, error) {
+	request := map[string]interface{}{
+		"query": strings.Replace(query, "%bucket", f.options.Bucket, -1),
+		"type":  "flux",
+		"dialect": map[string]interface{}{
+			"header":      true,
+			"annotations": []string{"datatype", "group"},
+		},
+	}
+
+	res, body, errs := gorequest.New().
+		Post(f.url("/api/v2/query")).
+		Set("Authorization", "Token "+f.options.Token).
+		Set("Accept", "application/csv").
+		Send(request).
+		Timeout(15 * time.Second).
+		End()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	if res.StatusCode != 200 {
+		return nil, apiError(res.StatusCode, body)
+	}
+
+	return parseFluxCsv(strings.NewReader(body))
+}
+
+/*
+ * Writes points in line protocol to bucket.
+ */
+func (f *fluxClient) write(points ...*client.Point) error {
+	lines := make([]string, 0, len(points))
+	for _, p := range points {
+		lines = append(lines, p.String())
+	}
+
+	res, body, errs := gorequest.New().
+		Post(f.url("/api/v2/write")).
+		Set("Authorization", "Token "+f.options.Token).
+		Type(gorequest.TypeText).
+		Send(strings.Join(lines, "\n")).
+		Timeout(15 * time.Second).
+		End()
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	if !(res.StatusCode >= 200 && res.StatusCode < 300) {
+		return apiError(res.StatusCode, body)
+	}
+
+	return nil
+}
+
+/*
+ * Errors of 2.x api are json objects with "code" and "message" fields.
+ */
+func apiError(status int, body string) error {
+	answer := struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{}
+
+	if err := json.Unmarshal([]byte(body), &answer); err != nil || answer.Message == "" {
+		return fmt.Errorf("influx2 responds with code %d", status)
+	}
+
+	return fmt.Errorf("influx2 responds with code %d: %s", status, answer.Message)
+}
+
+/*
+ * Parses annotated CSV (with "datatype" and "group" annotations) answer of Flux query.
+ * Numbers are returned as json.Number and other values as strings like in 1.x api.
+ */
+func parseFluxCsv(r io.Reader) (*client.Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // tables of different shape are in one answer
+
+	result := &client.Result{}
+
+	var datatypes, groups, header []string
+	var row *models.Row
+	table := ""
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case record[0] == "#datatype":
+			datatypes, header = record, nil
+			continue
+		case record[0] == "#group":
+			groups, header = record, nil
+			continue
+		case strings.HasPrefix(record[0], "#"):
+			continue
+		case header == nil:
+			header = record
+			continue
+		}
+
+		values := make(map[string]interface{})
+		for n := 1; n < len(record) && n < len(header); n++ {
+			values[header[n]] = fluxValue(record[n], annotation(datatypes, n))
+		}
+
+		if msg, ok := values["error"]; ok && values["result"] == nil {
+			return nil, fmt.Errorf("flux error: %v", msg)
+		}
+
+		if id := fmt.Sprintf("%v|%v", values["result"], values["table"]); row == nil || id != table {
+			result.Series = append(result.Series, newFluxRow(header, groups, values))
+			row, table = &result.Series[len(result.Series)-1], id
+		}
+
+		line := make([]interface{}, 0, len(row.Columns))
+		for _, column := range row.Columns {
+			line = append(line, values[column])
+		}
+		row.Values = append(row.Values, line)
+	}
+
+	return result, nil
+}
+
+/*
+ * Creates series for first record of table.
+ */
+func newFluxRow(header, groups []string, values map[string]interface{}) models.Row {
+	row := models.Row{
+		Tags:    make(map[string]string),
+		Columns: []string{"_time"},
+	}
+
+	if _, ok := values["_value"]; ok {
+		row.Columns = append(row.Columns, "_value")
+	}
+
+	for n := 1; n < len(header); n++ {
+		column := header[n]
+		switch {
+		case column == "result" || column == "table" || column == "_start" || column == "_stop":
+		case column == "_time" || column == "_value":
+		case annotation(groups, n) == "true":
+			row.Tags[column] = fmt.Sprintf("%v", values[column])
+		default:
+			row.Columns = append(row.Columns, column)
+		}
+	}
+
+	if name, ok := values["_measurement"].(string); ok {
+		row.Name = name
+	}
+
+	return row
+}
+
+func annotation(annotations []string, n int) string {
+	if n < len(annotations) {
+		return annotations[n]
+	}
+	return ""
+}
+
+func fluxValue(raw, datatype string) interface{} {
+	if raw == "" && datatype != "string" {
+		return nil
+	}
+
+	switch datatype {
+	case "double", "long", "unsignedLong":
+		return json.Number(raw)
+	default:
+		return raw
+	}
+}
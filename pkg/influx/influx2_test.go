@@ -0,0 +1,132 @@
+package influx
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fluxAnswer = `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,string,string,double
+#group,false,false,true,true,true,true,false
+#default,_result,,,,,,
+,result,table,_start,_stop,_field,host,_value
+,,0,2019-01-01T00:00:00Z,2019-01-01T00:05:00Z,usage_user,web1,12.5
+,,1,2019-01-01T00:00:00Z,2019-01-01T00:05:00Z,usage_user,web2,91
+
+#datatype,string,long,dateTime:RFC3339,double,double
+#group,false,false,false,false,false
+#default,p,,,,
+,result,table,_time,p95,p99
+,,2,2019-01-01T00:00:00Z,250,
+`
+
+/*
+ * Fake api of influxdb 2 which answers every query with provided csv and records requests.
+ */
+func flux2Api(answer string, requests chan *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		requests <- r
+
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": "unauthorized", "message": "unauthorized access"}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v2/query":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte(answer))
+		case "/api/v2/write":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func newFlux2Options(address string) Influx2Options {
+	options := DefaultInflux2Options()
+	options.Address = address
+	options.Org = "ops"
+	options.Token = "secret"
+	options.Bucket = "metrics"
+	return options
+}
+
+func TestFluxCsv(t *testing.T) {
+	res, err := parseFluxCsv(strings.NewReader(fluxAnswer))
+	assert.NoError(t, err)
+	assert.Len(t, res.Series, 3)
+
+	web2 := res.Series[1]
+	assert.Equal(t, map[string]string{"_field": "usage_user", "host": "web2"}, web2.Tags)
+	assert.Equal(t, []string{"_time", "_value"}, web2.Columns)
+	assert.Equal(t, [][]interface{}{{nil, json.Number("91")}}, web2.Values)
+
+	value, err := columnValue(web2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(91), value, "_value is checked by default")
+
+	pivot := res.Series[2]
+	assert.Len(t, pivot.Tags, 0)
+	assert.Equal(t, []string{"_time", "p95", "p99"}, pivot.Columns)
+	assert.Equal(t, [][]interface{}{{"2019-01-01T00:00:00Z", json.Number("250"), nil}}, pivot.Values)
+
+	_, err = parseFluxCsv(strings.NewReader("#datatype,string,string\n#group,true,true\n#default,,\n,error,reference\n,\"error calling function \"\"mean\"\"\",\n"))
+	assert.EqualError(t, err, `flux error: error calling function "mean"`)
+}
+
+func TestInflux2Query(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(flux2Api(fluxAnswer, requests))
+	defer server.Close()
+
+	i := NewInflux2(newFlux2Options(server.URL))
+	assert.Equal(t, "influx2", i.GetName())
+
+	values, err := i.querySeries(`from(bucket: "%bucket") |> range(start: -5m)`, "", nil)
+	assert.NoError(t, err)
+	assert.Len(t, values, 3)
+	assert.Equal(t, "web1", values[0].Tags["host"])
+	assert.Equal(t, 12.5, values[0].Value)
+
+	req := <-requests
+	assert.Equal(t, "/api/v2/query", req.URL.Path)
+	assert.Equal(t, "ops", req.URL.Query().Get("org"))
+
+	query := struct{ Query, Type string }{}
+	json.NewDecoder(req.Body).Decode(&query)
+	assert.Equal(t, `from(bucket: "metrics") |> range(start: -5m)`, query.Query)
+	assert.Equal(t, "flux", query.Type)
+
+	options := newFlux2Options(server.URL)
+	options.Token = "wrong"
+	_, err = NewInflux2(options).queryMultipleColumns("buckets()")
+	assert.EqualError(t, err, "influx2 responds with code 401: unauthorized access")
+}
+
+func TestInflux2Metrics(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(flux2Api("", requests))
+	defer server.Close()
+
+	metrics := NewInflux2Metrics(newFlux2Options(server.URL))
+	err := metrics.Save(domain.Message{Level: domain.MSG_LVL_CRIT, From: "influx2", Title: "cpu"})
+	assert.NoError(t, err)
+
+	req := <-requests
+	assert.Equal(t, "/api/v2/write", req.URL.Path)
+	assert.Equal(t, "metrics", req.URL.Query().Get("bucket"))
+	assert.Equal(t, "ops", req.URL.Query().Get("org"))
+
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.True(t, strings.HasPrefix(string(body), "fuse,from=influx2,level=crit,title=cpu event=1i "), string(body))
+}
@@ -0,0 +1,231 @@
+package influx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"fuse/pkg/domain"
+
+	"github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAlerter struct {
+	sent []domain.Message
+}
+
+func (a *fakeAlerter) GetName() string                         { return "fake" }
+func (a *fakeAlerter) Good(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Warn(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Crit(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Report(incident *domain.Incident) error  { return nil }
+func (a *fakeAlerter) Resolve(incident *domain.Incident) error { return nil }
+func (a *fakeAlerter) ConfigureHTTP(*http.ServeMux, string)    {}
+
+/*
+ * Fake influx which answers every query with provided series.
+ */
+func influxApi(series *[]map[string]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"statement_id": 0, "series": *series}},
+		})
+	}
+}
+
+func hostSeries(host string, value float64) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    "system",
+		"tags":    map[string]string{"host": host},
+		"columns": []string{"time", "mean"},
+		"values":  [][]interface{}{{"2019-01-01T00:00:00Z", value}},
+	}
+}
+
+func newPerSeriesInflux(address string) (*Influx, *Check, *fakeAlerter) {
+	options := DefaultInfluxOptions()
+	options.Address = address
+	options.Alert = "fake"
+
+	i := NewInflux(options)
+	i.AddTemplate(&Template{Name: "load", Body: "select mean(load1) from system where time > now() - %window group by host", Args: []string{"window"}})
+
+	trigger := domain.NewTrigger(nil)
+	trigger.AddState(&domain.State{Name: "good", Cycles: 1, Value: float64(4), Operator: "<"})
+	trigger.AddState(&domain.State{Name: "crit", Cycles: 1, Value: float64(4), Operator: ">="})
+
+	check := &Check{Template: "load", Info: "load average", Values: []string{"5m"}, Trigger: trigger, PerSeries: true}
+	i.AddCheck(check)
+
+	alerter := &fakeAlerter{}
+	notifer := domain.NewNotifer()
+	notifer.AddAlerter("fake", alerter)
+
+	i.notifer = notifer
+	i.setupTriggers()
+
+	return i, check, alerter
+}
+
+func loop(t *testing.T, i *Influx, check *Check) {
+	values, err := i.querySeriesOfCheck(check, nil)
+	assert.NoError(t, err)
+	i.touchSeries(check, values)
+}
+
+func TestInfluxPerSeriesTriggers(t *testing.T) {
+	series := []map[string]interface{}{hostSeries("web1", 1), hostSeries("web2", 7)}
+	server := httptest.NewServer(influxApi(&series))
+	defer server.Close()
+
+	i, check, alerter := newPerSeriesInflux(server.URL)
+
+	loop(t, i, check)
+	assert.Len(t, check.GetSeries(), 2)
+	assert.Equal(t, "good", check.Trigger.GetState(), "trigger of check is only a prototype")
+
+	// only web2 is in bad state
+	assert.Len(t, alerter.sent, 1)
+	msg := alerter.sent[0]
+	assert.Equal(t, domain.MSG_LVL_CRIT, msg.Level)
+	assert.Equal(t, "web2", msg.Args["host"])
+	assert.Equal(t, "5m", msg.Args["window"])
+	assert.Equal(t, "host=web2", msg.Details["series"])
+	assert.Equal(t, "QUERY: load average [host=web2] in CRIT state", msg.Title)
+
+	web1, web2 := check.GetSeries()[0], check.GetSeries()[1]
+	assert.Equal(t, "host=web1", web1.Key)
+	assert.Equal(t, web2.GetReportId(), msg.ReportId)
+	assert.NotEqual(t, web1.GetReportId(), web2.GetReportId())
+	assert.NotEqual(t, check.GetReportId(), web2.GetReportId())
+
+	_, ok := i.notifer.Incidents.Get(web2.GetReportId())
+	assert.True(t, ok)
+
+	// series are exposed as separate checks
+	checks := i.GetChecks()
+	assert.Len(t, checks, 2)
+	assert.Equal(t, "load average [host=web2]", checks[1].GetName())
+	assert.Equal(t, web2.GetReportId(), checks[1].GetReportId())
+
+	value, err := checks[1].Run(false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(7), value)
+}
+
+func TestInfluxStaleSeries(t *testing.T) {
+	series := []map[string]interface{}{hostSeries("web1", 1), hostSeries("web2", 7)}
+	server := httptest.NewServer(influxApi(&series))
+	defer server.Close()
+
+	i, check, alerter := newPerSeriesInflux(server.URL)
+	loop(t, i, check)
+	reportId := check.GetSeries()[1].GetReportId()
+
+	// web2 is gone
+	series = series[:1]
+	for n := 1; n < SERIES_STALE_LOOPS; n++ {
+		loop(t, i, check)
+		assert.Len(t, check.GetSeries(), 2, "series is kept for a few loops")
+	}
+
+	loop(t, i, check)
+	assert.Len(t, check.GetSeries(), 1)
+	assert.Equal(t, "host=web1", check.GetSeries()[0].Key)
+
+	_, ok := i.notifer.Incidents.Get(reportId)
+	assert.False(t, ok, "incident of stale series must be resolved")
+
+	last := alerter.sent[len(alerter.sent)-1]
+	assert.Equal(t, domain.MSG_LVL_GOOD, last.Level)
+	assert.Equal(t, reportId, last.ReportId)
+	assert.Equal(t, "web2", last.Args["host"])
+
+	// series which comes back starts from scratch
+	series = append(series, hostSeries("web2", 1))
+	loop(t, i, check)
+	assert.Len(t, check.GetSeries(), 2)
+	assert.Equal(t, "good", check.GetSeries()[1].Trigger.GetState())
+}
+
+func TestInfluxEmptyResultOfSeries(t *testing.T) {
+	series := []map[string]interface{}{hostSeries("web1", 1), hostSeries("web2", 1)}
+	server := httptest.NewServer(influxApi(&series))
+	defer server.Close()
+
+	i, check, alerter := newPerSeriesInflux(server.URL)
+	loop(t, i, check)
+	assert.Len(t, alerter.sent, 0)
+
+	// query returns nothing at all: series are not removed and their triggers get nil
+	series = series[:0]
+	for n := 0; n < SERIES_STALE_LOOPS+1; n++ {
+		loop(t, i, check)
+	}
+
+	assert.Len(t, check.GetSeries(), 2)
+	assert.Len(t, alerter.sent, 2)
+	for _, msg := range alerter.sent {
+		assert.Equal(t, domain.MSG_LVL_CRIT, msg.Level, "missing data is not a recovery")
+	}
+}
+
+func TestInfluxColumns(t *testing.T) {
+	var queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results": [{"statement_id": 0, "series": [{"name": "http", "columns": ["time", "p95", "p99"], "values": [["2019-01-01T00:00:00Z", 250, 1200]]}]}]}`))
+	}))
+	defer server.Close()
+
+	options := DefaultInfluxOptions()
+	options.Address = server.URL
+	i := NewInflux(options)
+
+	p95 := &Check{Template: "latency", Info: "api latency (p95)", Column: "p95"}
+	p99 := &Check{Template: "latency", Info: "api latency (p99)", Column: "p99"}
+	first := &Check{Template: "latency", Info: "api latency"}
+	assert.NotEqual(t, p95.GetReportId(), p99.GetReportId())
+
+	sql := "select percentile(duration, 95) as p95, percentile(duration, 99) as p99 from http"
+	cache := make(queryCache)
+
+	value, err := i.queryColumn(sql, p99.Column, cache)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1200), value)
+
+	value, err = i.queryColumn(sql, p95.Column, cache)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(250), value)
+
+	value, err = i.queryColumn(sql, first.Column, cache)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(250), value, "first column is checked by default")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&queries), "checks of one query share its result during loop")
+
+	_, err = i.queryColumn(sql, "p50", nil)
+	assert.EqualError(t, err, `column "p50" is missing in result (columns: time, p95, p99)`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queries))
+}
+
+func TestInfluxPreviewOfSeries(t *testing.T) {
+	res := &client.Result{Series: []models.Row{
+		{Tags: map[string]string{"host": "web1"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{"t1", 1}, {"t2", 2}}},
+		{Tags: map[string]string{"host": "web2"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{"t1", 3}}},
+	}}
+
+	table := previewTable(res)
+	assert.Equal(t, []string{"host", "time", "value"}, table.Columns)
+	assert.Equal(t, [][]string{{"web1", "t1", "1"}, {"web1", "t2", "2"}, {"web2", "t1", "3"}}, table.Rows)
+
+	// tags of single series are not shown
+	table = previewTable(&client.Result{Series: res.Series[:1]})
+	assert.Equal(t, []string{"time", "value"}, table.Columns)
+	assert.Len(t, table.Rows, 2)
+}
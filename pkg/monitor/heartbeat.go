@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/parnurzeal/gorequest"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ * Dead-man's-switch for fuse itself: periodically pings external watchdog url,
+ * but only while every monitor keeps finishing its check loops.
+ */
+type Heartbeat struct {
+	Url        string
+	Interval   time.Duration // how often to ping the watchdog
+	StaleAfter time.Duration // monitor is considered stuck if its last loop is older than this
+}
+
+// monitors with slower loops are stuck only after this amount of missed loops
+const STALE_LOOPS = 3
+
+func NewHeartbeat(url string, interval time.Duration) *Heartbeat {
+	return &Heartbeat{
+		Url:        url,
+		Interval:   interval,
+		StaleAfter: 3 * interval,
+	}
+}
+
+/*
+ * Runs ping loop forever (must be called in separate gorutine).
+ */
+func (h *Heartbeat) RunWith(monitors []Monitor) {
+	for {
+		time.Sleep(h.Interval)
+		h.Check(monitors)
+	}
+}
+
+/*
+ * Pings watchdog if all monitors are alive.
+ * Returns false if ping was skipped or failed.
+ */
+func (h *Heartbeat) Check(monitors []Monitor) bool {
+	now := time.Now()
+	for _, monitor := range monitors {
+		staleAfter := h.StaleAfter
+		if loops := STALE_LOOPS * monitor.GetInterval(); loops > staleAfter {
+			staleAfter = loops
+		}
+
+		last := monitor.GetLastLoop()
+		if now.Sub(last) > staleAfter {
+			log.WithFields(log.Fields{"monitor": monitor.GetName(), "last_loop": last}).
+				Warn("heartbeat: monitor is stuck, skipping ping")
+			return false
+		}
+	}
+
+	if err := h.ping(); err != nil {
+		log.WithError(err).WithField("url", h.Url).Error("heartbeat: can't ping watchdog")
+		return false
+	}
+
+	log.WithField("url", h.Url).Debug("heartbeat: ping sent")
+	return true
+}
+
+func (h *Heartbeat) ping() error {
+	res, _, errs := gorequest.New().
+		Get(h.Url).
+		Timeout(10 * time.Second).
+		End()
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	if !(res.StatusCode >= 200 && res.StatusCode < 300) {
+		return fmt.Errorf("watchdog responds with code %d", res.StatusCode)
+	}
+
+	return nil
+}
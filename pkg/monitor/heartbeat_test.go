@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMonitor struct {
+	Pulse
+}
+
+func (m *fakeMonitor) GetName() string                 { return "fake" }
+func (m *fakeMonitor) RunWith(notifer *domain.Notifer) {}
+func (m *fakeMonitor) LogInfo()                        {}
+
+func newWatchdog() (*httptest.Server, *int32) {
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+	}))
+	return server, &pings
+}
+
+func TestHeartbeatPingsWhenMonitorsAlive(t *testing.T) {
+	server, pings := newWatchdog()
+	defer server.Close()
+
+	m1, m2 := &fakeMonitor{}, &fakeMonitor{}
+	m1.Beat(time.Second)
+	m2.Beat(time.Second)
+
+	h := NewHeartbeat(server.URL, time.Minute)
+	assert.True(t, h.Check([]Monitor{m1, m2}), "heartbeat must ping when all monitors are alive")
+	assert.Equal(t, int32(1), atomic.LoadInt32(pings))
+}
+
+func TestHeartbeatSkipsWhenMonitorIsStuck(t *testing.T) {
+	server, pings := newWatchdog()
+	defer server.Close()
+
+	alive, stuck := &fakeMonitor{}, &fakeMonitor{}
+	alive.Beat(time.Second)
+	atomic.StoreInt64(&stuck.last, time.Now().Add(-time.Hour).UnixNano())
+
+	h := NewHeartbeat(server.URL, time.Minute)
+	assert.False(t, h.Check([]Monitor{alive, stuck}), "heartbeat must not ping when one of monitors is stuck")
+
+	// monitor which never finished a loop is stuck too
+	assert.False(t, h.Check([]Monitor{alive, &fakeMonitor{}}))
+	assert.Equal(t, int32(0), atomic.LoadInt32(pings))
+}
+
+func TestHeartbeatReportsWatchdogErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	m := &fakeMonitor{}
+	m.Beat(time.Second)
+
+	h := NewHeartbeat(server.URL, time.Minute)
+	assert.False(t, h.Check([]Monitor{m}))
+}
+
+func TestHeartbeatRespectsIntervalOfMonitor(t *testing.T) {
+	server, pings := newWatchdog()
+	defer server.Close()
+
+	// last loop was 10 minutes ago, but monitor runs its checks every 5 minutes
+	slow := &fakeMonitor{}
+	slow.Beat(5 * time.Minute)
+	atomic.StoreInt64(&slow.last, time.Now().Add(-10*time.Minute).UnixNano())
+
+	h := NewHeartbeat(server.URL, time.Minute)
+	assert.True(t, h.Check([]Monitor{slow}), "slow monitor is not stuck before it misses its loops")
+
+	atomic.StoreInt64(&slow.last, time.Now().Add(-20*time.Minute).UnixNano())
+	assert.False(t, h.Check([]Monitor{slow}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(pings))
+}
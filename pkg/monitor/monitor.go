@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"sync"
+	"time"
 
 	"fuse/pkg/config"
 	"fuse/pkg/domain"
@@ -10,12 +11,15 @@ import (
 )
 
 type Fuse struct {
-	Monitors []Monitor
+	Monitors  []Monitor
+	Heartbeat *Heartbeat // optional external watchdog
 }
 
 type Monitor interface {
 	GetName() string
 	RunWith(notifer *domain.Notifer)
+	GetLastLoop() time.Time     // time of last completed check loop (see Pulse)
+	GetInterval() time.Duration // pause between check loops (see Pulse)
 
 	LogInfo()
 }
@@ -30,7 +34,11 @@ func (f *Fuse) AddMonitor(monitor Monitor) {
 	f.Monitors = append(f.Monitors, monitor)
 }
 
-func (f *Fuse) RunWith(notifer *domain.Notifer) {
+/*
+ * Starts monitor's gorutines and waits for them.
+ * Returns error only if notifer can't be started.
+ */
+func (f *Fuse) RunWith(notifer *domain.Notifer) error {
 	var wg sync.WaitGroup
 
 	// TODO: send to another alter if slack is not available
@@ -42,7 +50,14 @@ func (f *Fuse) RunWith(notifer *domain.Notifer) {
 		})
 	}
 
-	notifer.Start()
+	if err := notifer.Start(); err != nil {
+		return err
+	}
+
+	if f.Heartbeat != nil {
+		log.WithField("url", f.Heartbeat.Url).Info("monitor: starting heartbeat gorutine")
+		go f.Heartbeat.RunWith(f.Monitors)
+	}
 
 	wg.Add(len(f.Monitors))
 	for _, monitor := range f.Monitors {
@@ -53,4 +68,6 @@ func (f *Fuse) RunWith(notifer *domain.Notifer) {
 		}(monitor)
 	}
 	wg.Wait()
+
+	return nil
 }
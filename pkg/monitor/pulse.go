@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * Pulse keeps time of the last completed check loop of monitor and the pause
+ * before the next one. Monitors embed it and call Beat() at the end of every loop,
+ * so it is safe to read the values from another gorutine (see Heartbeat).
+ */
+type Pulse struct {
+	last     int64 // unix time in nanoseconds
+	interval int64 // expected pause until next loop in nanoseconds
+}
+
+func (p *Pulse) Beat(interval time.Duration) {
+	atomic.StoreInt64(&p.interval, int64(interval))
+	atomic.StoreInt64(&p.last, time.Now().UnixNano())
+}
+
+/*
+ * Returns time of the last Beat() or zero time if monitor hasn't finished any loop yet.
+ */
+func (p *Pulse) GetLastLoop() time.Time {
+	last := atomic.LoadInt64(&p.last)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+/*
+ * Returns pause until next loop reported by the last Beat().
+ */
+func (p *Pulse) GetInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&p.interval))
+}
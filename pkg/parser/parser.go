@@ -1,15 +1,22 @@
 package parser
 
 import (
+	"crypto/x509"
 	"errors"
+	"io/ioutil"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"fuse/pkg/consul"
 	"fuse/pkg/domain"
 	"fuse/pkg/influx"
 	"fuse/pkg/monitor"
+	"fuse/pkg/probe"
+	"fuse/pkg/prometheus"
 	"fuse/pkg/slack"
+	"fuse/pkg/sql"
 	"fuse/pkg/twilio"
 
 	log "github.com/sirupsen/logrus"
@@ -25,6 +32,11 @@ type ParseResult struct {
 	Alerters map[string]domain.Alerter
 	Monitors map[string]monitor.Monitor
 	Metrics  map[string]domain.Metric
+
+	Heartbeat *monitor.Heartbeat
+	Http      domain.HttpOptions
+	Schedules *domain.Schedules // on-call schedules shared between alerters
+	Checks    *domain.Checks    // checks of all monitors for chat-ops
 }
 
 // helper class for parsing
@@ -33,11 +45,47 @@ type StateValue struct {
 	value    interface{}
 }
 
+// helper class for parsing
+type ColumnTrigger struct {
+	Column  string
+	Trigger *domain.Trigger
+}
+
+// helper class for parsing
+type ProbeTrigger struct {
+	Value   string
+	Trigger *domain.Trigger
+}
+
+// helper class for parsing (probe or endpoint)
+type ProbeSpec struct {
+	Name     string
+	Options  map[string]string
+	Triggers []*ProbeTrigger
+}
+
 // helper class for parsing
 type OptionalAlert struct {
 	Name string
 }
 
+// helper class for parsing
+type ServiceMode struct {
+	Name string
+}
+
+// helper class for parsing
+type OncallMembers struct {
+	Members []string
+}
+
+// helper class for parsing
+type OncallOverride struct {
+	Member string
+	From   string
+	To     string
+}
+
 func Parse(text string) (*ParseResult, error) {
 	// remove any comments from config
 	re := regexp.MustCompile(`(?m)^\s*#.*$`)
@@ -76,14 +124,18 @@ func logConfig(conf *ParseResult) {
 
 func getParser() *Parser {
 	result := &ParseResult{
-		make(map[string]domain.Alerter),
-		make(map[string]monitor.Monitor),
-		make(map[string]domain.Metric),
+		Alerters:  make(map[string]domain.Alerter),
+		Monitors:  make(map[string]monitor.Monitor),
+		Metrics:   make(map[string]domain.Metric),
+		Heartbeat: nil,
+		Http:      domain.DefaultHttpOptions(),
+		Schedules: domain.NewSchedules(),
+		Checks:    domain.NewChecks(),
 	}
 
 	parser, _ := NewParser(`
 		CONFIG  ← SECTION+
-		SECTION ← SLACK / TWILIO / CONSUL / INFLUX
+		SECTION ← SLACK / TWILIO / CONSUL / INFLUX2 / INFLUX / PROMETHEUS / SQL / HEARTBEAT / HTTP_PROBE / HTTP / TLS / TCP / DNS / EXEC / ONCALL
 
 		# Slack
 		SLACK   ← 'slack' '{' OPTION+ '}'
@@ -91,15 +143,56 @@ func getParser() *Parser {
 		# Twilio
 		TWILIO   ← 'twilio' '{' OPTION+ '}'
 
+		# On-call schedules
+		ONCALL          ← 'oncall' STRING '{' ONCALL_ITEM+ '}'
+		ONCALL_ITEM     ← ONCALL_MEMBERS / ONCALL_OVERRIDE / ONCALL_OPTION
+		ONCALL_MEMBERS  ← 'members' STRING+
+		ONCALL_OVERRIDE ← 'override' STRING 'from' STRING 'to' STRING
+		ONCALL_OPTION   ← < 'rotation' / 'handoff' / 'timezone' / 'start' > (STRING / VALUE)
+
+		# Http listener
+		HTTP    ← 'http' '{' OPTION* '}'
+
+		# Probes of http endpoints
+		HTTP_PROBE    ← 'http_probe' '{' OPTION* PROBE+ '}'
+		PROBE         ← 'probe' STRING '{' (EXPECT / OPTION)* PROBE_TRIGGER* '}'
+		EXPECT        ← < 'expect_' [a-z]+ > '~' REGEXP
+		PROBE_TRIGGER ← FNAME TRIGGER
+		REGEXP        ← '/' < ('\\/' / !'/' .)+ > '/'
+
+		# Certificates of tls endpoints
+		TLS      ← 'tls' '{' OPTION* ENDPOINT+ '}'
+		ENDPOINT ← 'endpoint' STRING ('{' OPTION* PROBE_TRIGGER* '}')?
+
+		# Tcp and dns probes
+		TCP      ← 'tcp' '{' OPTION* PROBE+ '}'
+		DNS      ← 'dns' '{' OPTION* PROBE+ '}'
+
+		# Nagios plugins
+		EXEC       ← 'exec' '{' OPTION* EXEC_CHECK+ '}'
+		EXEC_CHECK ← 'check' STRING '{' OPTION* PROBE_TRIGGER* '}'
+
+		# Heartbeat
+		HEARTBEAT ← 'heartbeat' '{' OPTION+ '}'
+
 		# Consul
-		CONSUL  ← 'consul' '{' OPTION+ SERVICE+ '}'
-		SERVICE ← 'service' STRING ALERT* TRIGGER?
+		CONSUL  ← 'consul' '{' OPTION+ (SERVICE / KV)+ '}'
+		SERVICE ← 'service' STRING MODE? ALERT* TRIGGER?
+		MODE    ← 'mode' '(' < STRING > ')'
+		KV      ← 'kv' STRING ALERT* TRIGGER
 		ALERT   ← 'alert' '(' < STRING > ')'
 
 		# Influx
 		INFLUX   ← 'influx' '{' OPTION+ TEMPLATE+ 'checks' '{' CHECK+ '}' '}'
+		INFLUX2  ← 'influx2' '{' OPTION+ TEMPLATE+ 'checks' '{' CHECK+ '}' '}'
+
+		# Prometheus (templates and checks of influx with PromQL queries)
+		PROMETHEUS ← 'prometheus' '{' OPTION+ TEMPLATE+ 'checks' '{' CHECK+ '}' '}'
+		SQL        ← 'sql' STRING '{' OPTION+ TEMPLATE+ 'checks' '{' CHECK+ '}' '}'
 		TEMPLATE ← 'template' FNAME '(' ARGS ')' '{' BODY '}' ('preview' '{' BODY '}')?
-		CHECK    ← FNAME '(' (STRING ',')* STRING ')' 'as' STRING TRIGGER
+		CHECK    ← FNAME '(' (STRING ',')* STRING ')' 'as' STRING PER_SERIES? (COLUMN+ / TRIGGER)
+		COLUMN   ← 'using' 'column' STRING TRIGGER
+		PER_SERIES ← 'per' 'series'
 
 		# Trigger
 		TRIGGER     ← STATE+
@@ -108,13 +201,14 @@ func getParser() *Parser {
 		COMPARATOR  ← < '<=' / '>=' / '<' / '>' / '=' >
 
 		# Basic items
-		OPTION  ←  KEY '=' STRING
+		OPTION  ←  KEY '=' (STRING / VALUE)
 		STRING  ←  '"' < (!'"' .)+ > '"'
 
 		FNAME   ←  < (![ \n(] .)+ >
 		ARG     ←  < (![ ,)] .)+ >  # any chars except space, ',' or ')'
 		ARGS    ←  (ARG ',')* ARG
-		BODY    ←  < (!'}' .)+ >
+		BODY    ←  < (BRACES / !'}' .)+ >
+		BRACES  ←  '{' (BRACES / !'}' .)* '}'  # balanced braces, e.g. records in Flux
 
 		KEY     ←  < (![ =] .)+ >
 		VALUE   ←  < (![ \n] .)+ >
@@ -132,58 +226,202 @@ func getParser() *Parser {
 	}
 
 	g["SLACK"].Action = func(v *Values, d Any) (Any, error) {
-		options := parseOptions(v)
+		options := parseSlackOptions(v)
 
-		//spew.Dump(options)
-		channel, ok := options["channel"]
-		if !ok {
+		if options.Channel == "" {
 			log.Fatal("Slack: 'channel' option is required!")
 		}
 
-		token, ok := options["token"]
-		if !ok {
+		if options.Token == "" {
 			log.Fatal("Slack: 'token' option is required!")
 		}
 
-		icon := options["icon_url"]
-
-		//spew.Dump("CREATING SLACK:", channel, token, icon)
-		result.Alerters["slack"] = slack.NewSlackClient(channel, token, icon)
+		result.Alerters["slack"] = slack.NewSlackClient(options, result.Schedules, result.Checks)
 		return nil, nil
 	}
 
 	g["TWILIO"].Action = func(v *Values, d Any) (Any, error) {
+		options := parseTwilioOptions(v)
+
+		required := map[string]string{
+			"phone_to":   options.PhoneTo,
+			"phone_from": options.PhoneFrom,
+			"sid":        options.Sid,
+			"token":      options.Token,
+			"twiml_url":  options.TwimlUrl,
+		}
+		for key, value := range required {
+			if value == "" {
+				log.Fatalf("Twilio: '%s' option is required!", key)
+			}
+		}
+
+		result.Alerters["twilio"] = twilio.NewTwilioClient(options, result.Schedules)
+
+		return nil, nil
+	}
+
+	g["ONCALL"].Action = func(v *Values, d Any) (Any, error) {
+		schedule := domain.NewSchedule(v.ToStr(0))
 		options := parseOptions(v)
 
-		//spew.Dump(options)
-		phoneTo, ok := options["phone_to"]
-		if !ok {
-			log.Fatal("Twilio: 'phone_to' option is required!")
+		// timezone must be known before parsing of any time
+		if timezone, ok := options["timezone"]; ok {
+			location, err := time.LoadLocation(timezone)
+			if err != nil {
+				log.WithError(err).Fatalf("oncall '%s': wrong timezone", schedule.Name)
+			}
+			schedule.Location = location
 		}
 
-		phoneFrom, ok := options["phone_from"]
-		if !ok {
-			log.Fatal("Twilio: 'phone_from' option is required!")
+		if rotation, ok := options["rotation"]; ok {
+			if rotation != domain.ROTATION_DAILY && rotation != domain.ROTATION_WEEKLY {
+				log.Fatalf("oncall '%s': rotation must be 'daily' or 'weekly'", schedule.Name)
+			}
+			schedule.Rotation = rotation
 		}
 
-		sid, ok := options["sid"]
-		if !ok {
-			log.Fatal("Twilio: 'sid' option is required!")
+		if handoff, ok := options["handoff"]; ok {
+			if err := schedule.SetHandoff(handoff); err != nil {
+				log.WithError(err).Fatalf("oncall '%s': wrong handoff", schedule.Name)
+			}
 		}
 
-		token, ok := options["token"]
-		if !ok {
-			log.Fatal("Twilio: 'token' option is required!")
+		if start, ok := options["start"]; ok {
+			schedule.Start = parseScheduleTime(schedule, start)
+		}
+
+		for _, any := range v.Vs {
+			switch item := any.(type) {
+			case *OncallMembers:
+				schedule.Members = append(schedule.Members, item.Members...)
+			case *OncallOverride:
+				schedule.AddOverride(item.Member, parseScheduleTime(schedule, item.From), parseScheduleTime(schedule, item.To))
+			}
+		}
+
+		if len(schedule.Members) == 0 {
+			log.Fatalf("oncall '%s': at least one member is required", schedule.Name)
+		}
+
+		result.Schedules.Add(schedule)
+		return nil, nil
+	}
+
+	g["ONCALL_MEMBERS"].Action = func(v *Values, d Any) (Any, error) {
+		members := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			members = append(members, v.ToStr(i))
+		}
+		return &OncallMembers{members}, nil
+	}
+
+	g["ONCALL_OVERRIDE"].Action = func(v *Values, d Any) (Any, error) {
+		return &OncallOverride{
+			Member: v.ToStr(0),
+			From:   v.ToStr(1),
+			To:     v.ToStr(2),
+		}, nil
+	}
+
+	g["ONCALL_OPTION"].Action = func(v *Values, d Any) (Any, error) {
+		return &Option{
+			Key:   v.Token(),
+			Value: v.ToStr(0),
+		}, nil
+	}
+
+	// registers monitor of probes with its checks
+	addProbeMonitor := func(name string, probes []*probe.Probe) {
+		if _, ok := result.Monitors[name]; ok {
+			log.Fatalf("%s: section of probes is defined twice", name)
+		}
+
+		probeMonitor := probe.NewMonitor(name, probes)
+		result.Checks.AddSource(probeMonitor)
+		result.Monitors[name] = probeMonitor
+	}
+
+	httpDefined := false
+	g["HTTP"].Action = func(v *Values, d Any) (Any, error) {
+		if httpDefined {
+			log.Fatal("http: section is defined twice")
 		}
 
-		twimlUrl, ok := options["twiml_url"]
+		httpDefined = true
+		result.Http = parseHttpOptions(v)
+		return nil, nil
+	}
+
+	g["HTTP_PROBE"].Action = func(v *Values, d Any) (Any, error) {
+		addProbeMonitor("http", parseProbes(v, map[string]string{}, parseHttpProbe))
+		return nil, nil
+	}
+
+	g["TLS"].Action = func(v *Values, d Any) (Any, error) {
+		addProbeMonitor("tls", parseProbes(v, map[string]string{"interval": "1h"}, parseTlsEndpoint))
+		return nil, nil
+	}
+
+	g["TCP"].Action = func(v *Values, d Any) (Any, error) {
+		addProbeMonitor("tcp", parseProbes(v, map[string]string{}, parseTcpProbe))
+		return nil, nil
+	}
+
+	g["DNS"].Action = func(v *Values, d Any) (Any, error) {
+		addProbeMonitor("dns", parseProbes(v, map[string]string{}, parseDnsProbe))
+		return nil, nil
+	}
+
+	g["EXEC"].Action = func(v *Values, d Any) (Any, error) {
+		addProbeMonitor("exec", parseProbes(v, map[string]string{"interval": "1m"}, parseExecCheck))
+		return nil, nil
+	}
+
+	g["PROBE"].Action = func(v *Values, d Any) (Any, error) {
+		return &ProbeSpec{
+			Name:     v.ToStr(0),
+			Options:  parseOptions(v),
+			Triggers: parseProbeTriggers(v),
+		}, nil
+	}
+
+	g["ENDPOINT"].Action = g["PROBE"].Action
+	g["EXEC_CHECK"].Action = g["PROBE"].Action
+
+	g["EXPECT"].Action = func(v *Values, d Any) (Any, error) {
+		return &Option{
+			Key:   v.Token(),
+			Value: v.ToStr(0),
+		}, nil
+	}
+
+	g["REGEXP"].Action = func(v *Values, d Any) (Any, error) {
+		return strings.Replace(v.Token(), "\\/", "/", -1), nil
+	}
+
+	g["PROBE_TRIGGER"].Action = func(v *Values, d Any) (Any, error) {
+		trigger, _ := v.Vs[1].(*domain.Trigger)
+		return &ProbeTrigger{
+			Value:   v.ToStr(0),
+			Trigger: trigger,
+		}, nil
+	}
+
+	g["HEARTBEAT"].Action = func(v *Values, d Any) (Any, error) {
+		options := parseOptions(v)
+
+		url, ok := options["url"]
 		if !ok {
-			log.Fatal("Twilio: 'twiml_url' option is required!")
+			log.Fatal("Heartbeat: 'url' option is required!")
 		}
 
-		//spew.Dump("CREATING TWILIO:", phoneTo, phoneFrom, token, sid, twimlUrl)
-		result.Alerters["twilio"] = twilio.NewTwilioClient(phoneTo, phoneFrom, token, sid, twimlUrl)
+		interval := parseDuration("heartbeat", options, "interval", time.Minute)
+
+		heartbeat := monitor.NewHeartbeat(url, interval)
+		heartbeat.StaleAfter = parseDuration("heartbeat", options, "stale_after", heartbeat.StaleAfter)
 
+		result.Heartbeat = heartbeat
 		return nil, nil
 	}
 
@@ -191,13 +429,21 @@ func getParser() *Parser {
 		options := parseOptions(v)
 
 		services := make([]*consul.Service, 0, v.Len())
+		keys := make([]*consul.Key, 0)
 		for _, any := range v.Vs {
 			if service, ok := any.(*consul.Service); ok {
 				services = append(services, service)
 			}
+
+			if key, ok := any.(*consul.Key); ok {
+				keys = append(keys, key)
+			}
 		}
 
-		result.Monitors["consul"] = consul.NewConsul(services, options)
+		consulMonitor := consul.NewConsul(services, keys, options)
+		result.Checks.AddSource(consulMonitor)
+
+		result.Monitors["consul"] = consulMonitor
 		return nil, nil
 	}
 
@@ -205,10 +451,15 @@ func getParser() *Parser {
 		//spew.Dump("SERVICE", v)
 		service := &consul.Service{
 			Name:   v.ToStr(0),
+			Mode:   consul.MODE_STATUS,
 			Alerts: make([]string, 0),
 		}
 
 		for i := 1; i < v.Len(); i++ {
+			if mode, ok := v.Vs[i].(*ServiceMode); ok {
+				service.Mode = mode.Name
+			}
+
 			if alert, ok := v.Vs[i].(*OptionalAlert); ok {
 				service.Alerts = append(service.Alerts, alert.Name)
 			}
@@ -221,6 +472,36 @@ func getParser() *Parser {
 		return service, nil
 	}
 
+	g["MODE"].Action = func(v *Values, d Any) (Any, error) {
+		mode := v.ToStr(0)
+		switch mode {
+		case consul.MODE_STATUS, consul.MODE_COUNT, consul.MODE_RATIO:
+		default:
+			log.Fatalf("consul: unknown mode '%s', use one of: %s, %s, %s", mode, consul.MODE_STATUS, consul.MODE_COUNT, consul.MODE_RATIO)
+		}
+
+		return &ServiceMode{mode}, nil
+	}
+
+	g["KV"].Action = func(v *Values, d Any) (Any, error) {
+		key := &consul.Key{
+			Path:   v.ToStr(0),
+			Alerts: make([]string, 0),
+		}
+
+		for i := 1; i < v.Len(); i++ {
+			if alert, ok := v.Vs[i].(*OptionalAlert); ok {
+				key.Alerts = append(key.Alerts, alert.Name)
+			}
+
+			if trigger, ok := v.Vs[i].(*domain.Trigger); ok {
+				key.Trigger = trigger
+			}
+		}
+
+		return key, nil
+	}
+
 	g["ALERT"].Action = func(v *Values, d Any) (Any, error) {
 		return &OptionalAlert{v.ToStr(0)}, nil
 	}
@@ -285,17 +566,97 @@ func getParser() *Parser {
 		for _, value := range v.Vs {
 			if template, ok := value.(*influx.Template); ok {
 				iflux.AddTemplate(template)
-			} else if check, ok := value.(*influx.Check); ok {
-				iflux.AddCheck(check)
+			} else if checks, ok := value.([]*influx.Check); ok {
+				for _, check := range checks {
+					iflux.AddCheck(check)
+				}
 			}
 		}
 
+		result.Checks.AddSource(iflux)
 		result.Monitors["influx"] = iflux
 		result.Metrics["influx"] = influx.NewInfluxMetrics(options)
 
 		return nil, nil
 	}
 
+	g["INFLUX2"].Action = func(v *Values, d Any) (Any, error) {
+		options := parseInflux2Options(v)
+		for key, value := range map[string]string{"org": options.Org, "token": options.Token} {
+			if value == "" {
+				log.Fatalf("Influx2: '%s' option is required!", key)
+			}
+		}
+
+		iflux := influx.NewInflux2(options)
+
+		for _, value := range v.Vs {
+			if template, ok := value.(*influx.Template); ok {
+				iflux.AddTemplate(template)
+			} else if checks, ok := value.([]*influx.Check); ok {
+				for _, check := range checks {
+					iflux.AddCheck(check)
+				}
+			}
+		}
+
+		result.Checks.AddSource(iflux)
+		result.Monitors["influx2"] = iflux
+		result.Metrics["influx2"] = influx.NewInflux2Metrics(options)
+
+		return nil, nil
+	}
+
+	g["PROMETHEUS"].Action = func(v *Values, d Any) (Any, error) {
+		options := parsePrometheusOptions(v)
+		prom := prometheus.NewPrometheus(options)
+
+		for _, value := range v.Vs {
+			if template, ok := value.(*influx.Template); ok {
+				prom.AddTemplate(template)
+			} else if checks, ok := value.([]*influx.Check); ok {
+				for _, check := range checks {
+					prom.AddCheck(check)
+				}
+			}
+		}
+
+		result.Checks.AddSource(prom)
+		result.Monitors["prometheus"] = prom
+
+		return nil, nil
+	}
+
+	g["SQL"].Action = func(v *Values, d Any) (Any, error) {
+		options := parseSqlOptions(v)
+		for key, value := range map[string]string{"driver": options.Driver, "dsn": options.Dsn} {
+			if value == "" {
+				log.Fatalf("sql '%s': '%s' option is required!", options.Name, key)
+			}
+		}
+
+		db := sql.NewSql(options)
+
+		for _, value := range v.Vs {
+			if template, ok := value.(*influx.Template); ok {
+				db.AddTemplate(template)
+			} else if checks, ok := value.([]*influx.Check); ok {
+				for _, check := range checks {
+					// result of sql query is a single series
+					if check.PerSeries {
+						log.Fatalf("sql '%s': 'per series' mode is not supported (check '%s')", options.Name, check.Info)
+					}
+					db.AddCheck(check)
+				}
+			}
+		}
+
+		result.Checks.AddSource(db)
+		result.Monitors[db.GetName()] = db
+
+		return nil, nil
+	}
+
 	g["TEMPLATE"].Action = func(v *Values, d Any) (Any, error) {
 		args, _ := v.Vs[1].([]string)
 		body := v.ToStr(2)
@@ -314,22 +675,61 @@ func getParser() *Parser {
 	}
 
 	g["CHECK"].Action = func(v *Values, d Any) (Any, error) {
-		values := make([]string, 0, v.Len()-2)
-		for i := 1; i < v.Len()-2; i++ {
+		// trailing values are: info, optional "per series" flag, trigger or triggers of columns
+		columns := make([]*ColumnTrigger, 0)
+		last := v.Len() - 1
+		for ; last > 0; last-- {
+			if trigger, ok := v.Vs[last].(*domain.Trigger); ok {
+				columns = append(columns, &ColumnTrigger{Trigger: trigger})
+			} else if column, ok := v.Vs[last].(*ColumnTrigger); ok {
+				columns = append([]*ColumnTrigger{column}, columns...)
+			} else {
+				break
+			}
+		}
+
+		perSeries, _ := v.Vs[last].(bool)
+		if perSeries {
+			last--
+		}
+
+		values := make([]string, 0, last-1)
+		for i := 1; i < last; i++ {
 			values = append(values, v.ToStr(i))
 		}
 
-		trigger, _ := v.Vs[v.Len()-1].(*domain.Trigger)
-		info, _ := v.Vs[v.Len()-2].(string)
+		checks := make([]*influx.Check, 0, len(columns))
+		for _, column := range columns {
+			info := v.ToStr(last)
+			if column.Column != "" {
+				info += " (" + column.Column + ")"
+			}
+
+			checks = append(checks, &influx.Check{
+				Template:  v.ToStr(0),
+				Info:      info,
+				Values:    values,
+				Trigger:   column.Trigger,
+				PerSeries: perSeries,
+				Column:    column.Column,
+			})
+		}
+
+		return checks, nil
+	}
 
-		return &influx.Check{
-			Template: v.ToStr(0),
-			Info:     info,
-			Values:   values,
-			Trigger:  trigger,
+	g["COLUMN"].Action = func(v *Values, d Any) (Any, error) {
+		trigger, _ := v.Vs[1].(*domain.Trigger)
+		return &ColumnTrigger{
+			Column:  v.ToStr(0),
+			Trigger: trigger,
 		}, nil
 	}
 
+	g["PER_SERIES"].Action = func(v *Values, d Any) (Any, error) {
+		return true, nil
+	}
+
 	g["ARG"].Action = func(v *Values, d Any) (Any, error) {
 		//spew.Dump("KEY", v.Token())
 		return v.Token(), nil
@@ -396,6 +796,467 @@ func parseOptions(v *Values) map[string]string {
 	return options
 }
 
+/*
+ * Creates probe of target with common options (interval, timeout, alert)
+ * and triggers of values (default triggers of target if there are no triggers).
+ */
+func parseProbe(name string, target probe.Target, options map[string]string, triggers []*ProbeTrigger) *probe.Probe {
+	section := "probe '" + name + "'"
+
+	p := &probe.Probe{
+		Name:     name,
+		Target:   target,
+		Interval: parseDuration(section, options, "interval", 30*time.Second),
+		Timeout:  parseDuration(section, options, "timeout", 10*time.Second),
+		Alerts:   parseList(options["alert"]),
+		Triggers: make(map[string]*domain.Trigger),
+	}
+
+	if len(p.Alerts) == 0 {
+		log.Fatalf("%s: 'alert' option is required!", section)
+	}
+
+	// any value can be checked if values are known only after run
+	_, dynamic := target.(probe.DynamicTarget)
+
+	for _, trigger := range triggers {
+		known := dynamic
+		for _, value := range target.GetValues() {
+			known = known || value == trigger.Value
+		}
+
+		if !known {
+			log.Fatalf("%s: unknown value '%s', use one of: %s", section, trigger.Value, strings.Join(target.GetValues(), ", "))
+		}
+
+		p.Triggers[trigger.Value] = trigger.Trigger
+	}
+
+	if len(p.Triggers) == 0 {
+		p.Triggers = target.DefaultTriggers()
+	}
+
+	return p
+}
+
+func parseProbeTriggers(v *Values) []*ProbeTrigger {
+	triggers := make([]*ProbeTrigger, 0)
+	for _, any := range v.Vs {
+		if trigger, ok := any.(*ProbeTrigger); ok {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
+
+/*
+ * Builds probes of section, defaults and options of section are defaults for options
+ * of probes.
+ */
+func parseProbes(v *Values, defaults map[string]string, build func(spec *ProbeSpec) *probe.Probe) []*probe.Probe {
+	inherited := make(map[string]string)
+	for key, value := range defaults {
+		inherited[key] = value
+	}
+	for key, value := range parseOptions(v) {
+		inherited[key] = value
+	}
+
+	probes := make([]*probe.Probe, 0)
+	for _, any := range v.Vs {
+		if spec, ok := any.(*ProbeSpec); ok {
+			options := make(map[string]string)
+			for key, value := range inherited {
+				options[key] = value
+			}
+			for key, value := range spec.Options {
+				options[key] = value
+			}
+
+			spec.Options = options
+			probes = append(probes, build(spec))
+		}
+	}
+	return probes
+}
+
+/*
+ * Compiles regexp option (e.g. "expect_body ~ /ok/"), returns nil if option is not set.
+ */
+func parseRegexp(section string, options map[string]string, key string) *regexp.Regexp {
+	expr, ok := options[key]
+	if !ok {
+		return nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		log.Fatalf("%s: wrong regexp in '%s': %s", section, key, err)
+	}
+	return re
+}
+
+func parseHttpProbe(spec *ProbeSpec) *probe.Probe {
+	section := "probe '" + spec.Name + "'"
+
+	url, ok := spec.Options["url"]
+	if !ok {
+		log.Fatalf("%s: 'url' option is required!", section)
+	}
+
+	target := probe.NewHttpTarget(url)
+	if method, ok := spec.Options["method"]; ok {
+		target.Method = strings.ToUpper(method)
+	}
+
+	if status, ok := spec.Options["expect_status"]; ok {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			log.Fatalf("%s: wrong format for 'expect_status': %s", section, err)
+		}
+		target.ExpectStatus = code
+	}
+
+	target.ExpectBody = parseRegexp(section, spec.Options, "expect_body")
+
+	return parseProbe(spec.Name, target, spec.Options, spec.Triggers)
+}
+
+func parseTcpProbe(spec *ProbeSpec) *probe.Probe {
+	section := "probe '" + spec.Name + "'"
+
+	address, ok := spec.Options["address"]
+	if !ok {
+		log.Fatalf("%s: 'address' option is required!", section)
+	}
+
+	target := probe.NewTcpTarget(address)
+	target.ExpectBanner = parseRegexp(section, spec.Options, "expect_banner")
+
+	return parseProbe(spec.Name, target, spec.Options, spec.Triggers)
+}
+
+func parseDnsProbe(spec *ProbeSpec) *probe.Probe {
+	section := "probe '" + spec.Name + "'"
+
+	for _, key := range []string{"record", "server"} {
+		if _, ok := spec.Options[key]; !ok {
+			log.Fatalf("%s: '%s' option is required!", section, key)
+		}
+	}
+
+	target, err := probe.NewDnsTarget(spec.Options["record"], spec.Options["server"])
+	if err != nil {
+		log.Fatalf("%s: %s", section, err)
+	}
+
+	if kind, ok := spec.Options["type"]; ok {
+		if err := target.SetType(kind); err != nil {
+			log.Fatalf("%s: %s", section, err)
+		}
+	}
+
+	target.ExpectAnswer = parseRegexp(section, spec.Options, "expect_answer")
+
+	return parseProbe(spec.Name, target, spec.Options, spec.Triggers)
+}
+
+func parseExecCheck(spec *ProbeSpec) *probe.Probe {
+	command, ok := spec.Options["command"]
+	if !ok {
+		log.Fatalf("check '%s': 'command' option is required!", spec.Name)
+	}
+
+	return parseProbe(spec.Name, probe.NewExecTarget(command), spec.Options, spec.Triggers)
+}
+
+func parseTlsEndpoint(spec *ProbeSpec) *probe.Probe {
+	address, options, triggers := spec.Name, spec.Options, spec.Triggers
+	target := probe.NewTlsTarget(address)
+
+	if name, ok := options["server_name"]; ok {
+		target.ServerName = name
+	}
+
+	if path, ok := options["ca_file"]; ok {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("tls '%s': can't read 'ca_file': %s", address, err)
+		}
+
+		target.Roots = x509.NewCertPool()
+		if !target.Roots.AppendCertsFromPEM(pem) {
+			log.Fatalf("tls '%s': no certificates in 'ca_file'", address)
+		}
+	}
+
+	return parseProbe(address, target, options, triggers)
+}
+
+/*
+ * Splits comma separated option value.
+ */
+func parseList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseScheduleTime(schedule *domain.Schedule, value string) time.Time {
+	t, err := schedule.ParseTime(value)
+	if err != nil {
+		log.WithError(err).Fatalf("oncall '%s': wrong time '%s', expected format '2006-01-02 15:04'", schedule.Name, value)
+	}
+	return t
+}
+
+func parseDuration(section string, options map[string]string, key string, def time.Duration) time.Duration {
+	value, ok := options[key]
+	if !ok {
+		return def
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.WithField("value", value).Fatalf("%s: wrong format for '%s': %s", section, key, err)
+	}
+
+	return duration
+}
+
+func parseSlackOptions(v *Values) slack.SlackOptions {
+	options := slack.DefaultSlackOptions()
+	for _, any := range v.Vs {
+		if option, ok := any.(*Option); ok {
+			switch option.Key {
+			case "channel":
+				options.Channel = option.Value
+			case "token":
+				options.Token = option.Value
+			case "icon_url":
+				options.IconUrl = option.Value
+			case "api_url":
+				options.ApiUrl = option.Value
+			case "mention":
+				options.Mention = option.Value
+			case "connection":
+				if option.Value != slack.CONNECTION_HTTP && option.Value != slack.CONNECTION_RTM {
+					log.Fatalf("slack: wrong connection '%s', use '%s' or '%s'", option.Value, slack.CONNECTION_HTTP, slack.CONNECTION_RTM)
+				}
+
+				options.Connection = option.Value
+			case "signing_secret":
+				options.SigningSecret = option.Value
+			case "replay_window":
+				window, err := time.ParseDuration(option.Value)
+				if err != nil {
+					log.Fatalln("slack: wrong format for replay_window: ", err)
+				}
+
+				options.ReplayWindow = window
+			case "allowed_users":
+				options.AllowedUsers = slack.ParseIdList(option.Value)
+			case "allowed_channels":
+				options.AllowedChannels = slack.ParseIdList(option.Value)
+			case "template_good", "template_warn", "template_crit":
+				parseTemplate("slack", options.Templates, option)
+			default:
+				log.WithField("option", option.Key).Warn("slack: unknown option")
+			}
+		}
+	}
+	return options
+}
+
+/*
+ * Sets per-level template of alerter from "template_{level}" option.
+ */
+func parseTemplate(section string, templates *domain.MessageTemplates, option *Option) {
+	msg := domain.Message{}
+	msg.ParseLevel(strings.TrimPrefix(option.Key, "template_"))
+
+	if err := templates.Set(msg.Level, option.Value); err != nil {
+		log.WithField("option", option.Key).Fatalf("%s: wrong template: %s", section, err)
+	}
+}
+
+func parseTwilioOptions(v *Values) twilio.TwilioOptions {
+	options := twilio.DefaultTwilioOptions()
+	for _, any := range v.Vs {
+		if option, ok := any.(*Option); ok {
+			switch option.Key {
+			case "phone_to":
+				options.PhoneTo = option.Value
+			case "phone_from":
+				options.PhoneFrom = option.Value
+			case "escalate_to":
+				options.EscalateTo = parseList(option.Value)
+			case "retries":
+				retries, err := strconv.Atoi(option.Value)
+				if err != nil || retries < 0 {
+					log.Fatalln("twilio: wrong format for retries: ", option.Value)
+				}
+
+				options.Retries = retries
+			case "retry_delay":
+				delay, err := time.ParseDuration(option.Value)
+				if err != nil {
+					log.Fatalln("twilio: wrong format for retry_delay: ", err)
+				}
+
+				options.RetryDelay = delay
+			case "sid":
+				options.Sid = option.Value
+			case "token":
+				options.Token = option.Value
+			case "twiml_url":
+				options.TwimlUrl = option.Value
+			case "api_url":
+				options.ApiUrl = option.Value
+			case "mode_good":
+				options.ModeGood = parseTwilioMode(option.Value)
+			case "mode_warn":
+				options.ModeWarn = parseTwilioMode(option.Value)
+			case "mode_crit":
+				options.ModeCrit = parseTwilioMode(option.Value)
+			case "voice":
+				options.Voice = option.Value
+			case "language":
+				options.Language = option.Value
+			case "play_url":
+				options.PlayUrl = option.Value
+			case "template_good", "template_warn", "template_crit":
+				parseTemplate("twilio", options.Templates, option)
+			default:
+				log.WithField("option", option.Key).Warn("twilio: unknown option")
+			}
+		}
+	}
+	return options
+}
+
+func parseTwilioMode(mode string) string {
+	switch mode {
+	case twilio.MODE_CALL, twilio.MODE_SMS, twilio.MODE_NONE:
+		return mode
+	default:
+		log.WithField("mode", mode).Fatal("twilio: wrong mode, must be one of: call, sms, none")
+		return ""
+	}
+}
+
+func parseHttpOptions(v *Values) domain.HttpOptions {
+	options := domain.DefaultHttpOptions()
+	for _, any := range v.Vs {
+		if option, ok := any.(*Option); ok {
+			switch option.Key {
+			case "listen":
+				options.Listen = option.Value
+			case "tls_cert":
+				options.TlsCert = option.Value
+			case "tls_key":
+				options.TlsKey = option.Value
+			case "base_path":
+				options.BasePath = option.Value
+			case "api_token":
+				options.ApiToken = option.Value
+			default:
+				log.WithField("option", option.Key).Warn("http: unknown option")
+			}
+		}
+	}
+	return options
+}
+
+func parseInflux2Options(v *Values) influx.Influx2Options {
+	options := influx.DefaultInflux2Options()
+	for _, any := range v.Vs {
+		if option, ok := any.(*Option); ok {
+			switch option.Key {
+			case "url":
+				options.Address = option.Value
+			case "org":
+				options.Org = option.Value
+			case "token":
+				options.Token = option.Value
+			case "bucket":
+				options.Bucket = option.Value
+			case "interval":
+				interval, err := strconv.Atoi(option.Value)
+				if err != nil {
+					log.Fatalln("influx2: wrong format for interval: ", err)
+				}
+
+				options.Interval = interval
+			case "alert":
+				options.Alert = option.Value
+			}
+		}
+	}
+
+	return options
+}
+
+func parsePrometheusOptions(v *Values) prometheus.PrometheusOptions {
+	options := prometheus.DefaultPrometheusOptions()
+	for _, any := range v.Vs {
+		if option, ok := any.(*Option); ok {
+			switch option.Key {
+			case "url":
+				options.Address = option.Value
+			case "interval":
+				interval, err := strconv.Atoi(option.Value)
+				if err != nil {
+					log.Fatalln("prometheus: wrong format for interval: ", err)
+				}
+
+				options.Interval = interval
+			case "alert":
+				options.Alert = option.Value
+			}
+		}
+	}
+	return options
+}
+
+func parseSqlOptions(v *Values) sql.SqlOptions {
+	options := sql.DefaultSqlOptions()
+	options.Name = v.ToStr(0)
+
+	for _, any := range v.Vs {
+		if option, ok := any.(*Option); ok {
+			switch option.Key {
+			case "driver":
+				options.Driver = option.Value
+			case "dsn":
+				options.Dsn = option.Value
+			case "timeout":
+				timeout, err := strconv.Atoi(option.Value)
+				if err != nil {
+					log.Fatalln("sql: wrong format for timeout: ", err)
+				}
+
+				options.Timeout = timeout
+			case "interval":
+				interval, err := strconv.Atoi(option.Value)
+				if err != nil {
+					log.Fatalln("sql: wrong format for interval: ", err)
+				}
+
+				options.Interval = interval
+			case "alert":
+				options.Alert = option.Value
+			}
+		}
+	}
+
+	return options
+}
+
 func parseInfluxOptions(v *Values) influx.InfluxOptions {
 	options := influx.DefaultInfluxOptions()
 	for _, any := range v.Vs {
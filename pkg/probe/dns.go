@@ -0,0 +1,262 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"fuse/pkg/domain"
+)
+
+const (
+	VALUE_TTL     = "ttl"     // minimal ttl of answers in seconds
+	VALUE_ANSWERS = "answers" // amount of answers
+)
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+}
+
+/*
+ * Record queried from specific resolver. Probe is ok if resolver answers without
+ * error, there is at least one answer and any answer matches expect_answer regexp (if set).
+ */
+type DnsTarget struct {
+	Record       dnsmessage.Name
+	Type         dnsmessage.Type
+	Server       string // host:port of resolver
+	ExpectAnswer *regexp.Regexp
+}
+
+func NewDnsTarget(record, server string) (*DnsTarget, error) {
+	if !strings.HasSuffix(record, ".") {
+		record += "."
+	}
+
+	name, err := dnsmessage.NewName(record)
+	if err != nil {
+		return nil, fmt.Errorf("wrong record '%s': %s", record, err)
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	return &DnsTarget{
+		Record: name,
+		Type:   dnsmessage.TypeA,
+		Server: server,
+	}, nil
+}
+
+/*
+ * Sets type of record by name ("A", "MX", etc).
+ */
+func (t *DnsTarget) SetType(name string) error {
+	kind, ok := dnsTypes[strings.ToUpper(name)]
+	if !ok {
+		types := make([]string, 0, len(dnsTypes))
+		for name := range dnsTypes {
+			types = append(types, name)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("unsupported type of record '%s', use one of: %s", name, strings.Join(types, ", "))
+	}
+
+	t.Type = kind
+	return nil
+}
+
+func (t *DnsTarget) GetQuery() string {
+	return fmt.Sprintf("dns://%s/%s?type=%s", t.Server, strings.TrimSuffix(t.Record.String(), "."), strings.TrimPrefix(t.Type.String(), "Type"))
+}
+
+func (t *DnsTarget) GetValues() []string {
+	return []string{VALUE_RESULT, VALUE_LATENCY, VALUE_TTL, VALUE_ANSWERS}
+}
+
+func (t *DnsTarget) DefaultTriggers() map[string]*domain.Trigger {
+	return map[string]*domain.Trigger{VALUE_RESULT: ResultTrigger()}
+}
+
+func (t *DnsTarget) Run(timeout time.Duration) *Result {
+	res := &Result{
+		Values:  map[string]interface{}{VALUE_RESULT: RESULT_FAIL, VALUE_LATENCY: nil, VALUE_TTL: nil, VALUE_ANSWERS: nil},
+		Details: map[string]string{"server": t.Server},
+	}
+
+	start := time.Now()
+	msg, err := t.exchange(timeout)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	latency := time.Since(start)
+	res.Values[VALUE_LATENCY] = float64(latency) / float64(time.Millisecond)
+	res.Details["latency"] = latency.Round(time.Millisecond).String()
+	res.Details["rcode"] = strings.TrimPrefix(msg.Header.RCode.String(), "RCode")
+
+	if msg.Header.RCode != dnsmessage.RCodeSuccess {
+		res.Error = "resolver answers with " + res.Details["rcode"]
+		return res
+	}
+
+	answers := make([]string, 0, len(msg.Answers))
+	var ttl uint32
+	for n, answer := range msg.Answers {
+		if n == 0 || answer.Header.TTL < ttl {
+			ttl = answer.Header.TTL
+		}
+		answers = append(answers, formatAnswer(answer.Body))
+	}
+
+	res.Values[VALUE_ANSWERS] = float64(len(answers))
+	res.Details["answers"] = strings.Join(answers, ", ")
+
+	switch {
+	case len(answers) == 0:
+		res.Error = "no answers"
+		return res
+	case t.ExpectAnswer != nil && !matchAny(t.ExpectAnswer, answers):
+		res.Error = fmt.Sprintf("answers don't match /%s/", t.ExpectAnswer)
+	default:
+		res.Values[VALUE_RESULT] = RESULT_OK
+	}
+
+	res.Values[VALUE_TTL] = float64(ttl)
+	return res
+}
+
+/*
+ * Sends query via udp and repeats it via tcp if answer is truncated.
+ */
+func (t *DnsTarget) exchange(timeout time.Duration) (*dnsmessage.Message, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: t.Record, Type: t.Type, Class: dnsmessage.ClassINET}},
+	}
+
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	answer, err := exchangeUdp(t.Server, packed, deadline)
+	if err == nil && answer.Header.Truncated {
+		answer, err = exchangeTcp(t.Server, packed, deadline)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if answer.Header.ID != id || !answer.Header.Response {
+		return nil, fmt.Errorf("wrong answer from resolver")
+	}
+
+	return answer, nil
+}
+
+func exchangeUdp(server string, query []byte, deadline time.Time) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout("udp", server, time.Until(deadline))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(deadline)
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 512) // max size of udp message without EDNS
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &dnsmessage.Message{}
+	return answer, answer.Unpack(buf[:n])
+}
+
+func exchangeTcp(server string, query []byte, deadline time.Time) (*dnsmessage.Message, error) {
+	conn, err := net.DialTimeout("tcp", server, time.Until(deadline))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(deadline)
+
+	// messages are prefixed with length in tcp
+	buf := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(buf, uint16(len(query)))
+	copy(buf[2:], query)
+
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return nil, err
+	}
+
+	buf = make([]byte, binary.BigEndian.Uint16(buf[:2]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	answer := &dnsmessage.Message{}
+	return answer, answer.Unpack(buf)
+}
+
+func formatAnswer(body dnsmessage.ResourceBody) string {
+	switch r := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(r.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(r.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return r.CNAME.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", r.Pref, r.MX)
+	case *dnsmessage.NSResource:
+		return r.NS.String()
+	case *dnsmessage.PTRResource:
+		return r.PTR.String()
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target)
+	case *dnsmessage.TXTResource:
+		return strings.Join(r.TXT, "")
+	default:
+		return fmt.Sprintf("%T", body)
+	}
+}
+
+func matchAny(re *regexp.Regexp, answers []string) bool {
+	for _, answer := range answers {
+		if re.MatchString(answer) {
+			return true
+		}
+	}
+	return false
+}
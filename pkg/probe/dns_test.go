@@ -0,0 +1,184 @@
+package probe
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+/*
+ * Resolver answers via udp and tcp on the same port:
+ *   api.test.   - two A records
+ *   empty.test. - no answers
+ *   big.test.   - truncated via udp, MX record via tcp
+ *   other       - NXDOMAIN
+ */
+type testResolver struct {
+	udp net.PacketConn
+	tcp net.Listener
+}
+
+func newTestResolver(t *testing.T) *testResolver {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		udp.Close()
+		t.Skip("tcp port of resolver is busy: ", err)
+	}
+
+	s := &testResolver{udp: udp, tcp: tcp}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if answer := s.answer(buf[:n], false); answer != nil {
+				udp.WriteTo(answer, addr)
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+
+			size := make([]byte, 2)
+			if _, err := io.ReadFull(conn, size); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(size))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					answer := s.answer(query, true)
+					binary.BigEndian.PutUint16(size, uint16(len(answer)))
+					conn.Write(append(size, answer...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	return s
+}
+
+func (s *testResolver) answer(query []byte, tcp bool) []byte {
+	msg := dnsmessage.Message{}
+	if err := msg.Unpack(query); err != nil || len(msg.Questions) != 1 {
+		return nil
+	}
+
+	question := msg.Questions[0]
+	msg.Header.Response = true
+
+	header := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 300}
+	switch question.Name.String() {
+	case "api.test.":
+		msg.Answers = []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+			{Header: header, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+		}
+		msg.Answers[1].Header.TTL = 60
+	case "empty.test.":
+	case "big.test.":
+		if !tcp {
+			msg.Header.Truncated = true
+			break
+		}
+		mx, _ := dnsmessage.NewName("mail.test.")
+		msg.Answers = []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.MXResource{Pref: 10, MX: mx}},
+		}
+	default:
+		msg.Header.RCode = dnsmessage.RCodeNameError
+	}
+
+	packed, _ := msg.Pack()
+	return packed
+}
+
+func (s *testResolver) Addr() string {
+	return s.udp.LocalAddr().String()
+}
+
+func (s *testResolver) Close() {
+	s.udp.Close()
+	s.tcp.Close()
+}
+
+func TestDnsTarget(t *testing.T) {
+	server := newTestResolver(t)
+	defer server.Close()
+
+	target, err := NewDnsTarget("api.test", server.Addr())
+	assert.NoError(t, err)
+	assert.Equal(t, "dns://"+server.Addr()+"/api.test?type=A", target.GetQuery())
+
+	res := target.Run(time.Second)
+	assert.Equal(t, "", res.Error)
+	assert.Equal(t, RESULT_OK, res.Values[VALUE_RESULT])
+	assert.Equal(t, float64(2), res.Values[VALUE_ANSWERS])
+	assert.Equal(t, float64(60), res.Values[VALUE_TTL], "minimal ttl of answers")
+	assert.IsType(t, float64(0), res.Values[VALUE_LATENCY])
+	assert.Equal(t, "10.0.0.1, 10.0.0.2", res.Details["answers"])
+
+	target.ExpectAnswer = regexp.MustCompile(`^10\.0\.0\.2$`)
+	assert.Equal(t, RESULT_OK, target.Run(time.Second).Values[VALUE_RESULT], "any answer can match")
+
+	target.ExpectAnswer = regexp.MustCompile(`^192\.168\.`)
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Equal(t, "answers don't match /^192\\.168\\./", res.Error)
+
+	target, _ = NewDnsTarget("empty.test.", server.Addr())
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Equal(t, float64(0), res.Values[VALUE_ANSWERS])
+	assert.Nil(t, res.Values[VALUE_TTL])
+	assert.Equal(t, "no answers", res.Error)
+
+	target, _ = NewDnsTarget("missing.test", server.Addr())
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Nil(t, res.Values[VALUE_ANSWERS])
+	assert.Equal(t, "resolver answers with NameError", res.Error)
+}
+
+func TestDnsTargetTruncated(t *testing.T) {
+	server := newTestResolver(t)
+	defer server.Close()
+
+	target, _ := NewDnsTarget("big.test", server.Addr())
+	assert.NoError(t, target.SetType("mx"))
+
+	res := target.Run(time.Second)
+	assert.Equal(t, "", res.Error)
+	assert.Equal(t, RESULT_OK, res.Values[VALUE_RESULT])
+	assert.Equal(t, "10 mail.test.", res.Details["answers"])
+}
+
+func TestDnsTargetOptions(t *testing.T) {
+	target, err := NewDnsTarget("example.com", "8.8.8.8")
+	assert.NoError(t, err)
+	assert.Equal(t, "8.8.8.8:53", target.Server, "default port")
+
+	assert.EqualError(t, target.SetType("SOA"), "unsupported type of record 'SOA', use one of: A, AAAA, CNAME, MX, NS, PTR, SRV, TXT")
+	assert.Equal(t, dnsmessage.TypeA, target.Type)
+
+	target, _ = NewDnsTarget("example.com", "127.0.0.1:1")
+	res := target.Run(100 * time.Millisecond)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.NotEqual(t, "", res.Error)
+}
@@ -0,0 +1,228 @@
+package probe
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"fuse/pkg/domain"
+)
+
+const (
+	VALUE_STATE = "state" // state of nagios plugin by exit code: "good", "warn", "crit" or "unknown"
+
+	STATE_GOOD    = "good"
+	STATE_WARN    = "warn"
+	STATE_CRIT    = "crit"
+	STATE_UNKNOWN = "unknown"
+
+	MAX_OUTPUT_SIZE = 1 << 16 // only first bytes of output are parsed
+	MAX_TEXT_SIZE   = 4096    // only first bytes of text are included in alerts
+)
+
+// exit codes of nagios plugins
+var execStates = []string{STATE_GOOD, STATE_WARN, STATE_CRIT, STATE_UNKNOWN}
+
+// value of perfdata with optional unit of measurement, e.g. "2643MB" or "0.05s"
+var perfValueRe = regexp.MustCompile(`^([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)[a-zA-Z%]*$`)
+
+/*
+ * Command in nagios plugin format: exit code is state of check (0 - good, 1 - warn,
+ * 2 - crit, 3 or anything else - unknown), stdout is text for alerts with optional
+ * perfdata after "|" (e.g. "DISK OK | /=2643MB;5948;5958;0;5968").
+ * Labels of perfdata are values for triggers (units are dropped).
+ */
+type ExecTarget struct {
+	Command string // command is run by shell
+}
+
+func NewExecTarget(command string) *ExecTarget {
+	return &ExecTarget{
+		Command: command,
+	}
+}
+
+func (t *ExecTarget) GetQuery() string {
+	return t.Command
+}
+
+func (t *ExecTarget) GetValues() []string {
+	return []string{VALUE_STATE}
+}
+
+/*
+ * DynamicTarget interface implementation: labels of perfdata are known only after run.
+ */
+func (t *ExecTarget) HasDynamicValues() bool {
+	return true
+}
+
+/*
+ * Default trigger maps state of plugin to the same state of trigger
+ * ("unknown" state is reported with warn level).
+ */
+func (t *ExecTarget) DefaultTriggers() map[string]*domain.Trigger {
+	trigger := domain.NewTrigger(nil)
+
+	trigger.AddState(&domain.State{Name: "good", Cycles: 1, Operator: "=", Value: STATE_GOOD})
+	trigger.AddState(&domain.State{Name: "warn", Cycles: 2, Operator: "=", Value: STATE_WARN})
+	trigger.AddState(&domain.State{Name: "unknown", Cycles: 2, Operator: "=", Value: STATE_UNKNOWN})
+	trigger.AddState(&domain.State{Name: "crit", Cycles: 2, Operator: "=", Value: STATE_CRIT})
+
+	return map[string]*domain.Trigger{VALUE_STATE: trigger}
+}
+
+func (t *ExecTarget) Run(timeout time.Duration) *Result {
+	res := &Result{
+		Values:  map[string]interface{}{VALUE_STATE: STATE_UNKNOWN},
+		Details: map[string]string{},
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("/bin/sh", "-c", t.Command)
+	cmd.Stdout = &stdout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} // plugin and its children are killed together
+
+	if err := cmd.Start(); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		res.Error = fmt.Sprintf("command is timed out after %s", timeout)
+		return res
+	}
+
+	code := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			res.Error = err.Error()
+			return res
+		}
+		code = exitErr.ExitCode()
+	}
+
+	state := STATE_UNKNOWN
+	if code >= 0 && code < len(execStates) {
+		state = execStates[code]
+	}
+
+	text, perfdata := parsePluginOutput(stdout.String())
+	for label, value := range perfdata {
+		if _, ok := res.Values[label]; !ok {
+			res.Values[label] = value
+		}
+	}
+
+	res.Values[VALUE_STATE] = state
+	res.Details["exit_code"] = strconv.Itoa(code)
+	res.Output = text
+
+	if line := firstLine(text); line != "" {
+		res.Details["output"] = line
+	}
+
+	if state != STATE_GOOD {
+		res.Error = fmt.Sprintf("exit code is %d", code)
+	}
+
+	return res
+}
+
+/*
+ * Splits output of nagios plugin to text and perfdata. Perfdata is after "|" of the first
+ * line and after "|" of any next line (long text is before it).
+ */
+func parsePluginOutput(output string) (string, map[string]float64) {
+	if len(output) > MAX_OUTPUT_SIZE {
+		output = output[:MAX_OUTPUT_SIZE]
+	}
+
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	text := make([]string, 0, len(lines))
+	perf := make([]string, 0)
+
+	long := false // rest of output after "|" in long text is perfdata
+	for n, line := range lines {
+		if long {
+			perf = append(perf, line)
+			continue
+		}
+
+		if i := strings.Index(line, "|"); i >= 0 {
+			perf = append(perf, line[i+1:])
+			line = line[:i]
+			long = n > 0
+		}
+		text = append(text, strings.TrimRight(line, " "))
+	}
+
+	result := strings.TrimSpace(strings.Join(text, "\n"))
+	if len(result) > MAX_TEXT_SIZE {
+		result = result[:MAX_TEXT_SIZE] + "..."
+	}
+
+	return result, parsePerfdata(strings.Join(perf, " "))
+}
+
+/*
+ * Parses perfdata: space separated 'label'=value[UOM];[warn];[crit];[min];[max].
+ * Labels with spaces are quoted, unknown values ("U") are skipped.
+ */
+func parsePerfdata(perfdata string) map[string]float64 {
+	values := make(map[string]float64)
+
+	rest := strings.TrimSpace(perfdata)
+	for rest != "" {
+		var label string
+		if strings.HasPrefix(rest, "'") {
+			end := strings.Index(rest[1:], "'")
+			if end < 0 {
+				break
+			}
+			label, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.Index(rest, "=")
+			if end < 0 || strings.ContainsAny(rest[:end], " \t") {
+				break
+			}
+			label, rest = rest[:end], rest[end:]
+		}
+
+		if !strings.HasPrefix(rest, "=") {
+			break
+		}
+
+		data := rest[1:]
+		if end := strings.IndexAny(data, " \t"); end >= 0 {
+			data, rest = data[:end], strings.TrimSpace(data[end:])
+		} else {
+			rest = ""
+		}
+
+		value := strings.SplitN(data, ";", 2)[0]
+		if match := perfValueRe.FindStringSubmatch(value); match != nil {
+			if number, err := strconv.ParseFloat(match[1], 64); err == nil {
+				values[label] = number
+			}
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,122 @@
+package probe
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fuse/pkg/domain"
+)
+
+func TestExecTarget(t *testing.T) {
+	target := NewExecTarget(`echo "DISK OK - free space: / 3326 MB (56%);| /=2643MB;5948;5958;0;5968"`)
+	assert.Equal(t, `echo "DISK OK - free space: / 3326 MB (56%);| /=2643MB;5948;5958;0;5968"`, target.GetQuery())
+
+	res := target.Run(time.Second)
+	assert.Equal(t, "", res.Error)
+	assert.Equal(t, STATE_GOOD, res.Values[VALUE_STATE])
+	assert.Equal(t, float64(2643), res.Values["/"])
+	assert.Equal(t, "DISK OK - free space: / 3326 MB (56%);", res.Output)
+	assert.Equal(t, "0", res.Details["exit_code"])
+
+	for code, state := range map[string]string{"1": STATE_WARN, "2": STATE_CRIT, "3": STATE_UNKNOWN, "42": STATE_UNKNOWN} {
+		res = NewExecTarget("echo 'CHECK RESULT'; exit " + code).Run(time.Second)
+		assert.Equal(t, state, res.Values[VALUE_STATE], "exit code "+code)
+		assert.Equal(t, "exit code is "+code, res.Error)
+		assert.Equal(t, "CHECK RESULT", res.Output)
+	}
+
+	res = NewExecTarget("/nonexistent/check_nothing").Run(time.Second)
+	assert.Equal(t, STATE_UNKNOWN, res.Values[VALUE_STATE], "shell exits with 127")
+	assert.Equal(t, "127", res.Details["exit_code"])
+
+	start := time.Now()
+	res = NewExecTarget("sleep 5").Run(100 * time.Millisecond)
+	assert.Equal(t, STATE_UNKNOWN, res.Values[VALUE_STATE])
+	assert.Equal(t, "command is timed out after 100ms", res.Error)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// children of shell are killed too
+	pidFile := filepath.Join(os.TempDir(), fmt.Sprintf("fuse-exec-%d.pid", os.Getpid()))
+	defer os.Remove(pidFile)
+
+	NewExecTarget("sleep 5 & echo $! > " + pidFile + "; wait").Run(100 * time.Millisecond)
+	data, err := ioutil.ReadFile(pidFile)
+	assert.NoError(t, err)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+
+	// killed child is reparented to init and can stay as zombie
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	alive := err == nil && !strings.Contains(string(stat), ") Z ")
+	assert.False(t, alive, "child of plugin must be killed on timeout")
+}
+
+func TestExecTargetTriggers(t *testing.T) {
+	trigger := NewExecTarget("true").DefaultTriggers()[VALUE_STATE]
+
+	assert.Equal(t, "good", trigger.Match(STATE_GOOD))
+	assert.Equal(t, "warn", trigger.Match(STATE_WARN))
+	assert.Equal(t, "unknown", trigger.Match(STATE_UNKNOWN))
+	assert.Equal(t, "crit", trigger.Match(STATE_CRIT))
+}
+
+func TestExecTargetAlerts(t *testing.T) {
+	target := NewExecTarget("echo 'DISK CRITICAL - free space: / 10 MB (1%)|/=5958MB;5948;5958;0;5968'; echo '/var is full'; exit 2")
+	m, p, alerter := newProbeMonitor(target, target.DefaultTriggers())
+	p.Timeout = time.Second
+
+	m.probe(p)
+	m.probe(p)
+
+	assert.Len(t, alerter.sent, 1)
+	assert.Equal(t, "PROBE: api in CRIT state", alerter.sent[0].Title)
+	assert.Equal(t, "Probe has bad state for more than 2m0s: exit code is 2.\n"+
+		"DISK CRITICAL - free space: / 10 MB (1%)\n/var is full", alerter.sent[0].Body)
+	assert.Equal(t, "DISK CRITICAL - free space: / 10 MB (1%)", alerter.sent[0].Details["output"])
+
+	checks := m.GetChecks()
+	assert.Len(t, checks, 1)
+	assert.Equal(t, "api", checks[0].GetName())
+}
+
+func TestExecTargetUnknownState(t *testing.T) {
+	target := NewExecTarget("echo 'UNKNOWN - no such device'; exit 3")
+	m, p, alerter := newProbeMonitor(target, target.DefaultTriggers())
+	p.Timeout = time.Second
+
+	m.probe(p)
+	m.probe(p)
+
+	assert.Len(t, alerter.sent, 1)
+	assert.Equal(t, "PROBE: api in UNKNOWN state", alerter.sent[0].Title)
+	assert.Equal(t, domain.MSG_LVL_WARN, alerter.sent[0].Level, "unknown state is sent as warn")
+}
+
+func TestParsePluginOutput(t *testing.T) {
+	text, perfdata := parsePluginOutput("PING OK - Packet loss = 0%, RTA = 0.80 ms|'round trip'=0.80ms;100;500 pl=0%;20;60;0\n" +
+		"long text\n" +
+		"more text | load1=0.5;4;8;0 load5=U\n" +
+		"'disk /var'=12.5GB\n")
+
+	assert.Equal(t, "PING OK - Packet loss = 0%, RTA = 0.80 ms\nlong text\nmore text", text)
+	assert.Equal(t, map[string]float64{
+		"round trip": 0.8,
+		"pl":         0,
+		"load1":      0.5,
+		"disk /var":  12.5,
+	}, perfdata)
+
+	text, perfdata = parsePluginOutput("")
+	assert.Equal(t, "", text)
+	assert.Empty(t, perfdata)
+
+	_, perfdata = parsePluginOutput("OK | time=1.5e-3s;;; size=-10B broken 'unclosed=1")
+	assert.Equal(t, map[string]float64{"time": 0.0015, "size": -10}, perfdata, "parsing stops on broken perfdata")
+}
@@ -0,0 +1,95 @@
+package probe
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"time"
+
+	"fuse/pkg/domain"
+)
+
+const (
+	VALUE_STATUS  = "status"  // http status code
+	VALUE_LATENCY = "latency" // time of request in milliseconds (including reading of body)
+
+	MAX_BODY_SIZE = 1 << 20 // only first megabyte of body is matched with expect_body
+)
+
+/*
+ * Http endpoint. Probe is ok if status is expected one (any 2xx if not set)
+ * and body matches expect_body regexp (if set).
+ */
+type HttpTarget struct {
+	Url          string
+	Method       string
+	ExpectStatus int
+	ExpectBody   *regexp.Regexp
+}
+
+func NewHttpTarget(url string) *HttpTarget {
+	return &HttpTarget{
+		Url:    url,
+		Method: "GET",
+	}
+}
+
+func (t *HttpTarget) GetQuery() string {
+	return t.Method + " " + t.Url
+}
+
+func (t *HttpTarget) GetValues() []string {
+	return []string{VALUE_RESULT, VALUE_STATUS, VALUE_LATENCY}
+}
+
+func (t *HttpTarget) DefaultTriggers() map[string]*domain.Trigger {
+	return map[string]*domain.Trigger{VALUE_RESULT: ResultTrigger()}
+}
+
+func (t *HttpTarget) Run(timeout time.Duration) *Result {
+	res := &Result{
+		Values:  map[string]interface{}{VALUE_RESULT: RESULT_FAIL, VALUE_STATUS: nil, VALUE_LATENCY: nil},
+		Details: map[string]string{"url": t.Url},
+	}
+
+	req, err := http.NewRequest(t.Method, t.Url, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, MAX_BODY_SIZE))
+	latency := time.Since(start)
+
+	res.Values[VALUE_STATUS] = float64(resp.StatusCode)
+	res.Values[VALUE_LATENCY] = float64(latency) / float64(time.Millisecond)
+	res.Details["status"] = resp.Status
+	res.Details["latency"] = latency.Round(time.Millisecond).String()
+
+	switch {
+	case err != nil:
+		res.Error = fmt.Sprintf("can't read body: %s", err)
+	case t.ExpectStatus != 0 && resp.StatusCode != t.ExpectStatus:
+		res.Error = fmt.Sprintf("status is %d instead of %d", resp.StatusCode, t.ExpectStatus)
+	case t.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 300):
+		res.Error = fmt.Sprintf("status is %d", resp.StatusCode)
+	case t.ExpectBody != nil && !t.ExpectBody.Match(body):
+		res.Error = fmt.Sprintf("body doesn't match /%s/", t.ExpectBody)
+	default:
+		res.Values[VALUE_RESULT] = RESULT_OK
+	}
+
+	return res
+}
@@ -0,0 +1,56 @@
+package probe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpTarget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status": "ok", "method": "` + r.Method + `"}`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	target := NewHttpTarget(server.URL + "/health")
+	target.ExpectBody = regexp.MustCompile(`"status":\s*"ok"`)
+
+	res := target.Run(time.Second)
+	assert.Equal(t, RESULT_OK, res.Values[VALUE_RESULT])
+	assert.Equal(t, float64(200), res.Values[VALUE_STATUS])
+	assert.IsType(t, float64(0), res.Values[VALUE_LATENCY])
+	assert.Equal(t, "", res.Error)
+	assert.Equal(t, "GET "+server.URL+"/health", target.GetQuery())
+
+	target.Method = "HEAD"
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Equal(t, `body doesn't match /"status":\s*"ok"/`, res.Error)
+
+	target = NewHttpTarget(server.URL + "/broken")
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Equal(t, float64(502), res.Values[VALUE_STATUS])
+	assert.Equal(t, "status is 502", res.Error)
+
+	target.ExpectStatus = 502
+	assert.Equal(t, RESULT_OK, target.Run(time.Second).Values[VALUE_RESULT], "any status can be expected")
+
+	target = NewHttpTarget(server.URL + "/slow")
+	res = target.Run(50 * time.Millisecond)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Nil(t, res.Values[VALUE_STATUS])
+	assert.Nil(t, res.Values[VALUE_LATENCY])
+	assert.Contains(t, res.Error, "Timeout")
+}
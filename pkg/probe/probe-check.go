@@ -0,0 +1,99 @@
+package probe
+
+import (
+	"fmt"
+	"sort"
+
+	"fuse/pkg/domain"
+)
+
+/*
+ * Exposes trigger of probe's value as check for chat-ops.
+ */
+type probeCheck struct {
+	monitor *Monitor
+	probe   *Probe
+	value   string
+}
+
+/*
+ * CheckSource interface implementation.
+ */
+func (m *Monitor) GetChecks() []domain.Check {
+	checks := make([]domain.Check, 0, len(m.probes))
+	for _, p := range m.probes {
+		for _, value := range p.GetValues() {
+			checks = append(checks, &probeCheck{m, p, value})
+		}
+	}
+	return checks
+}
+
+func (c *probeCheck) GetReportId() string {
+	return c.probe.GetReportId(c.value)
+}
+
+func (c *probeCheck) GetName() string {
+	return c.probe.GetSubject(c.value)
+}
+
+func (c *probeCheck) GetMonitor() string {
+	return c.monitor.GetName()
+}
+
+func (c *probeCheck) GetQuery() string {
+	return c.probe.Target.GetQuery()
+}
+
+func (c *probeCheck) GetTrigger() *domain.Trigger {
+	return domain.ReadyTrigger(c.monitor.GetLastLoop(), c.probe.Triggers[c.value])
+}
+
+func (c *probeCheck) Run(feed bool) (interface{}, error) {
+	res := c.probe.Run()
+	value := res.Values[c.value]
+
+	if trigger := c.GetTrigger(); feed && trigger != nil {
+		trigger.Touch(value)
+	}
+
+	if res.Error != "" && value == nil {
+		return nil, fmt.Errorf("%s", res.Error)
+	}
+	return value, nil
+}
+
+/*
+ * Values and details of last run as table.
+ */
+func (c *probeCheck) GetPreview() *domain.Table {
+	res := c.probe.GetLast()
+	if res == nil {
+		return nil
+	}
+
+	row := make(map[string]string)
+	for key, value := range res.Values {
+		row[key] = fmt.Sprintf("%v", value)
+	}
+	for key, detail := range res.Details {
+		row[key] = detail
+	}
+	if res.Error != "" {
+		row["error"] = res.Error
+	}
+
+	table := &domain.Table{}
+	for key := range row {
+		table.Columns = append(table.Columns, key)
+	}
+	sort.Strings(table.Columns)
+
+	values := make([]string, 0, len(table.Columns))
+	for _, key := range table.Columns {
+		values = append(values, row[key])
+	}
+	table.Rows = [][]string{values}
+
+	return table
+}
@@ -0,0 +1,296 @@
+package probe
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"fuse/pkg/domain"
+	"fuse/pkg/monitor"
+)
+
+const (
+	VALUE_RESULT = "result" // "ok" or "fail", checked by default trigger
+
+	RESULT_OK   = "ok"
+	RESULT_FAIL = "fail"
+)
+
+/*
+ * Monitor which periodically runs probes of targets (http endpoints, etc).
+ */
+type Monitor struct {
+	monitor.Pulse
+
+	name    string
+	probes  []*Probe
+	notifer *domain.Notifer // notifer to send alters to
+}
+
+/*
+ * Something to probe: http endpoint, tls certificate, etc.
+ */
+type Target interface {
+	Run(timeout time.Duration) *Result
+	GetQuery() string    // description of target for messages, e.g. "GET https://example.com"
+	GetValues() []string // names of values in result which can be checked by triggers
+
+	DefaultTriggers() map[string]*domain.Trigger // triggers for probe without configured ones
+}
+
+/*
+ * Target which values are known only after run (e.g. perfdata of nagios plugins),
+ * so triggers can check any value besides ones of GetValues (missing values are <nil>).
+ */
+type DynamicTarget interface {
+	Target
+	HasDynamicValues() bool
+}
+
+/*
+ * Result of probe: values for triggers and details for alert messages.
+ */
+type Result struct {
+	Values  map[string]interface{}
+	Details map[string]string
+	Error   string // reason of failure
+	Output  string // text for body of alerts (e.g. output of command)
+}
+
+type Probe struct {
+	Name     string
+	Target   Target
+	Interval time.Duration
+	Timeout  time.Duration
+	Alerts   []string                   // alert channels
+	Triggers map[string]*domain.Trigger // triggers by name of value
+
+	mu   sync.Mutex
+	last *Result   // result of last run
+	next time.Time // time of next run in monitor's loop
+}
+
+func NewMonitor(name string, probes []*Probe) *Monitor {
+	return &Monitor{
+		name:   name,
+		probes: probes,
+	}
+}
+
+/*
+ * Default trigger for "result" value: crit after two failed probes.
+ */
+func ResultTrigger() *domain.Trigger {
+	trigger := domain.NewTrigger(nil)
+
+	trigger.AddState(&domain.State{
+		Name:     "good",
+		Cycles:   1,
+		Operator: "=",
+		Value:    RESULT_OK,
+	})
+
+	trigger.AddState(&domain.State{
+		Name:     "crit",
+		Cycles:   2,
+		Operator: "=",
+		Value:    RESULT_FAIL,
+	})
+
+	return trigger
+}
+
+func (p *Probe) GetReportId(value string) string {
+	h := md5.New()
+	io.WriteString(h, p.Name+"|"+p.Target.GetQuery()+"|"+value)
+	return fmt.Sprintf("%.5x", h.Sum(nil))
+}
+
+/*
+ * Name of probe with name of value (except main values of targets).
+ */
+func (p *Probe) GetSubject(value string) string {
+	if value == VALUE_RESULT || value == VALUE_STATE {
+		return p.Name
+	}
+	return p.Name + " " + value
+}
+
+/*
+ * Returns names of checked values in stable order.
+ */
+func (p *Probe) GetValues() []string {
+	values := make([]string, 0, len(p.Triggers))
+	for value := range p.Triggers {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+/*
+ * Runs target and saves result.
+ */
+func (p *Probe) Run() *Result {
+	res := p.Target.Run(p.Timeout)
+
+	p.mu.Lock()
+	p.last = res
+	p.mu.Unlock()
+
+	return res
+}
+
+/*
+ * Returns result of last run (nil if probe was not run yet).
+ */
+func (p *Probe) GetLast() *Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+/*
+ * Monitor interface implementation.
+ */
+func (m *Monitor) GetName() string {
+	return m.name
+}
+
+/*
+ * Monitor interface implementation.
+ * Every probe is run with its own interval, probes which are due are run concurrently.
+ */
+func (m *Monitor) RunWith(notifer *domain.Notifer) {
+	m.notifer = notifer
+	m.setupTriggers()
+
+	for {
+		log.Infof("%s: probe loop...", m.name)
+
+		now := time.Now()
+		next := now.Add(time.Minute)
+
+		var wg sync.WaitGroup
+		for _, p := range m.probes {
+			if !now.Before(p.next) {
+				p.next = now.Add(p.Interval)
+
+				wg.Add(1)
+				go func(p *Probe) {
+					defer wg.Done()
+					m.probe(p)
+				}(p)
+			}
+
+			if p.next.Before(next) {
+				next = p.next
+			}
+		}
+		wg.Wait()
+
+		m.Beat(time.Until(next))
+		time.Sleep(time.Until(next))
+	}
+}
+
+/*
+ * Runs probe and sends its values to triggers.
+ */
+func (m *Monitor) probe(p *Probe) {
+	res := p.Run()
+
+	log.WithFields(log.Fields{"probe": p.Name, "values": res.Values, "error": res.Error}).Debugf("%s: probe is done", m.name)
+
+	for value, trigger := range p.Triggers {
+		trigger.Touch(res.Values[value])
+	}
+}
+
+/*
+ * Prepare callback of every trigger.
+ */
+func (m *Monitor) setupTriggers() {
+	for _, p := range m.probes {
+		for value, trigger := range p.Triggers {
+			trigger.SetupNilStates()
+			trigger.Callback = m.makeCallback(p, value)
+		}
+	}
+}
+
+func (m *Monitor) makeCallback(p *Probe, value string) func(*domain.State, interface{}) error {
+	subject := p.GetSubject(value)
+
+	return func(state *domain.State, lastValue interface{}) error {
+		duration := p.Interval * time.Duration(state.Cycles)
+
+		details := map[string]string{
+			"value": fmt.Sprintf("%v", lastValue),
+		}
+
+		var reason, output string
+		if res := p.GetLast(); res != nil {
+			for key, detail := range res.Details {
+				details[key] = detail
+			}
+			reason, output = res.Error, res.Output
+		}
+
+		var body string
+		switch {
+		case state.Name == "good":
+			body = fmt.Sprintf("Probe is good more than %s.", duration)
+		case value == VALUE_RESULT && reason != "":
+			body = fmt.Sprintf("Probe fails more than %s: %s.", duration, reason)
+		case reason != "":
+			body = fmt.Sprintf("Probe has bad %s for more than %s: %s.", value, duration, reason)
+		default:
+			body = fmt.Sprintf("Probe has bad %s for more than %s.", value, duration)
+		}
+
+		if output != "" {
+			body += "\n" + output
+		}
+
+		reportId := p.GetReportId(value)
+		msg := domain.Message{
+			From:     m.name,
+			ReportId: reportId,
+			Title:    fmt.Sprintf("PROBE: %s in %s state", subject, strings.ToUpper(state.Name)),
+			Body:     body,
+			Subject:  subject,
+			Duration: duration,
+			Query:    p.Target.GetQuery(),
+			Details:  details,
+		}
+
+		msg.ParseLevel(state.Name)
+
+		if msg.Level != domain.MSG_LVL_GOOD {
+			m.notifer.Report(reportId, msg)
+		}
+
+		m.notifer.Notify(state.Name, p.Alerts, msg)
+
+		// resolve after notification, so alerters can bind recovery message to incident
+		if msg.Level == domain.MSG_LVL_GOOD {
+			m.notifer.Resolve(reportId)
+		}
+
+		return nil
+	}
+}
+
+func (m *Monitor) LogInfo() {
+	log.WithField("monitor", m.GetName()).WithField("amount", len(m.probes)).Info("amount of probes")
+	for _, p := range m.probes {
+		log.WithField("monitor", m.GetName()).WithField("probe", p.Name).WithField("target", p.Target.GetQuery()).Info("probe")
+	}
+}
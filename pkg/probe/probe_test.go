@@ -0,0 +1,88 @@
+package probe
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeAlerter struct {
+	sent []domain.Message
+}
+
+func (a *fakeAlerter) GetName() string                         { return "fake" }
+func (a *fakeAlerter) Good(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Warn(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Crit(msg domain.Message) error           { a.sent = append(a.sent, msg); return nil }
+func (a *fakeAlerter) Report(incident *domain.Incident) error  { return nil }
+func (a *fakeAlerter) Resolve(incident *domain.Incident) error { return nil }
+func (a *fakeAlerter) ConfigureHTTP(*http.ServeMux, string)    {}
+
+/*
+ * Target which returns prepared result.
+ */
+type fakeTarget struct {
+	res *Result
+}
+
+func (t *fakeTarget) Run(timeout time.Duration) *Result { return t.res }
+func (t *fakeTarget) GetQuery() string                  { return "fake" }
+func (t *fakeTarget) GetValues() []string               { return []string{VALUE_RESULT, VALUE_LATENCY} }
+func (t *fakeTarget) DefaultTriggers() map[string]*domain.Trigger {
+	return map[string]*domain.Trigger{VALUE_RESULT: ResultTrigger()}
+}
+
+func newProbeMonitor(target Target, triggers map[string]*domain.Trigger) (*Monitor, *Probe, *fakeAlerter) {
+	p := &Probe{Name: "api", Target: target, Interval: time.Minute, Alerts: []string{"fake"}, Triggers: triggers}
+	m := NewMonitor("http", []*Probe{p})
+
+	alerter := &fakeAlerter{}
+	notifer := domain.NewNotifer()
+	notifer.AddAlerter("fake", alerter)
+
+	m.notifer = notifer
+	m.setupTriggers()
+
+	return m, p, alerter
+}
+
+func TestProbeTriggers(t *testing.T) {
+	latency := domain.NewTrigger(nil)
+	latency.AddState(&domain.State{Name: "good", Cycles: 1, Value: float64(1000), Operator: "<"})
+	latency.AddState(&domain.State{Name: "crit", Cycles: 1, Value: float64(2000), Operator: ">"})
+
+	target := &fakeTarget{&Result{Values: map[string]interface{}{VALUE_RESULT: RESULT_OK, VALUE_LATENCY: float64(2500)}}}
+	m, p, alerter := newProbeMonitor(target, map[string]*domain.Trigger{VALUE_RESULT: ResultTrigger(), VALUE_LATENCY: latency})
+
+	m.probe(p)
+	assert.Len(t, alerter.sent, 1)
+	assert.Equal(t, "PROBE: api latency in CRIT state", alerter.sent[0].Title)
+	assert.Equal(t, p.GetReportId(VALUE_LATENCY), alerter.sent[0].ReportId)
+	assert.Equal(t, "2500", alerter.sent[0].Details["value"])
+
+	// result is failed for two probes
+	target.res = &Result{Values: map[string]interface{}{VALUE_RESULT: RESULT_FAIL}, Error: "status is 502", Details: map[string]string{"status": "502 Bad Gateway"}}
+	m.probe(p)
+	m.probe(p)
+
+	msg := alerter.sent[len(alerter.sent)-1]
+	assert.Equal(t, "PROBE: api in CRIT state", msg.Title)
+	assert.Equal(t, "Probe fails more than 2m0s: status is 502.", msg.Body)
+	assert.Equal(t, "502 Bad Gateway", msg.Details["status"])
+	assert.Equal(t, "fake", msg.Query)
+
+	_, ok := m.notifer.Incidents.Get(p.GetReportId(VALUE_RESULT))
+	assert.True(t, ok)
+	assert.NotEqual(t, p.GetReportId(VALUE_RESULT), p.GetReportId(VALUE_LATENCY))
+
+	checks := m.GetChecks()
+	assert.Len(t, checks, 2)
+	assert.Equal(t, "api latency", checks[0].GetName())
+	assert.Equal(t, "api", checks[1].GetName())
+	assert.Nil(t, checks[1].GetTrigger(), "monitor is not started")
+	assert.Equal(t, []string{"error", "result", "status"}, checks[1].GetPreview().Columns)
+}
@@ -0,0 +1,102 @@
+package probe
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"fuse/pkg/domain"
+)
+
+const MAX_BANNER_SIZE = 4096 // only first bytes of banner are matched with expect_banner
+
+/*
+ * Tcp port. Probe is ok if connection is established and banner sent by
+ * server (e.g. "SSH-2.0-OpenSSH") matches expect_banner regexp (if set).
+ * Latency is time of connect in milliseconds.
+ */
+type TcpTarget struct {
+	Address      string
+	ExpectBanner *regexp.Regexp
+}
+
+func NewTcpTarget(address string) *TcpTarget {
+	return &TcpTarget{
+		Address: address,
+	}
+}
+
+func (t *TcpTarget) GetQuery() string {
+	return "tcp://" + t.Address
+}
+
+func (t *TcpTarget) GetValues() []string {
+	return []string{VALUE_RESULT, VALUE_LATENCY}
+}
+
+func (t *TcpTarget) DefaultTriggers() map[string]*domain.Trigger {
+	return map[string]*domain.Trigger{VALUE_RESULT: ResultTrigger()}
+}
+
+func (t *TcpTarget) Run(timeout time.Duration) *Result {
+	res := &Result{
+		Values:  map[string]interface{}{VALUE_RESULT: RESULT_FAIL, VALUE_LATENCY: nil},
+		Details: map[string]string{"address": t.Address},
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", t.Address, timeout)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	latency := time.Since(start)
+	res.Values[VALUE_LATENCY] = float64(latency) / float64(time.Millisecond)
+	res.Details["latency"] = latency.Round(time.Millisecond).String()
+
+	if t.ExpectBanner == nil {
+		res.Values[VALUE_RESULT] = RESULT_OK
+		return res
+	}
+
+	// banner can be sent in several packets, so read until it matches
+	conn.SetReadDeadline(start.Add(timeout))
+
+	banner := make([]byte, 0, MAX_BANNER_SIZE)
+	buf := make([]byte, MAX_BANNER_SIZE)
+	for len(banner) < MAX_BANNER_SIZE {
+		n, err := conn.Read(buf[:MAX_BANNER_SIZE-len(banner)])
+		banner = append(banner, buf[:n]...)
+
+		if t.ExpectBanner.Match(banner) {
+			res.Values[VALUE_RESULT] = RESULT_OK
+			break
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	res.Details["banner"] = firstLine(string(banner))
+	if res.Values[VALUE_RESULT] != RESULT_OK {
+		res.Error = fmt.Sprintf("banner doesn't match /%s/", t.ExpectBanner)
+	}
+
+	return res
+}
+
+/*
+ * First line of text limited to 100 chars.
+ */
+func firstLine(text string) string {
+	line := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	if runes := []rune(line); len(runes) > 100 {
+		line = string(runes[:97]) + "..."
+	}
+	return line
+}
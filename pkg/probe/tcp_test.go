@@ -0,0 +1,77 @@
+package probe
+
+import (
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTcpTarget(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			// banner is sent in several packets
+			for _, part := range []string{"SSH-2.0-", "OpenSSH_7.4\r\n"} {
+				conn.Write([]byte(part))
+				time.Sleep(10 * time.Millisecond)
+			}
+			conn.Close()
+		}
+	}()
+
+	address := listener.Addr().String()
+
+	target := NewTcpTarget(address)
+	res := target.Run(time.Second)
+	assert.Equal(t, RESULT_OK, res.Values[VALUE_RESULT])
+	assert.IsType(t, float64(0), res.Values[VALUE_LATENCY])
+	assert.Equal(t, "", res.Error)
+	assert.Equal(t, "tcp://"+address, target.GetQuery())
+
+	target.ExpectBanner = regexp.MustCompile(`^SSH-2\.0-OpenSSH`)
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_OK, res.Values[VALUE_RESULT])
+	assert.Equal(t, "SSH-2.0-OpenSSH_7.4", res.Details["banner"])
+
+	target.ExpectBanner = regexp.MustCompile(`^220 `)
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.IsType(t, float64(0), res.Values[VALUE_LATENCY], "connection is established")
+	assert.Equal(t, "banner doesn't match /^220 /", res.Error)
+
+	listener.Close()
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Nil(t, res.Values[VALUE_LATENCY])
+	assert.Contains(t, res.Error, "refused")
+}
+
+func TestTcpTargetBannerTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// server accepts connection (backlog), but never sends banner
+	target := NewTcpTarget(listener.Addr().String())
+	target.ExpectBanner = regexp.MustCompile(`.`)
+
+	start := time.Now()
+	res := target.Run(100 * time.Millisecond)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Equal(t, "", res.Details["banner"])
+	assert.True(t, time.Since(start) < time.Second)
+}
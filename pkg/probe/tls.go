@@ -0,0 +1,123 @@
+package probe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"fuse/pkg/domain"
+)
+
+const (
+	VALUE_DAYS = "days" // days until expiry of the first expiring certificate of peer chain
+
+	DAY = 24 * time.Hour
+)
+
+/*
+ * Certificate of tls endpoint. Probe fails if endpoint is not available,
+ * chain can't be verified (unknown authority, expired certificate, etc)
+ * or certificate doesn't match server name.
+ */
+type TlsTarget struct {
+	Address    string         // host:port
+	ServerName string         // name for SNI and verification (host of address by default)
+	Roots      *x509.CertPool // trusted roots (system roots if nil)
+}
+
+func NewTlsTarget(address string) *TlsTarget {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "443")
+	}
+
+	host, _, _ := net.SplitHostPort(address)
+
+	return &TlsTarget{
+		Address:    address,
+		ServerName: host,
+	}
+}
+
+func (t *TlsTarget) GetQuery() string {
+	return "tls://" + t.Address
+}
+
+func (t *TlsTarget) GetValues() []string {
+	return []string{VALUE_RESULT, VALUE_DAYS}
+}
+
+/*
+ * Checks result and warns 21 days (crit 7 days) before expiry.
+ */
+func (t *TlsTarget) DefaultTriggers() map[string]*domain.Trigger {
+	days := domain.NewTrigger(nil)
+	days.AddState(&domain.State{Name: "good", Cycles: 1, Operator: ">=", Value: float64(21)})
+	days.AddState(&domain.State{Name: "warn", Cycles: 1, Operator: "<", Value: float64(21)})
+	days.AddState(&domain.State{Name: "crit", Cycles: 1, Operator: "<", Value: float64(7)})
+
+	return map[string]*domain.Trigger{VALUE_RESULT: ResultTrigger(), VALUE_DAYS: days}
+}
+
+func (t *TlsTarget) Run(timeout time.Duration) *Result {
+	res := &Result{
+		Values:  map[string]interface{}{VALUE_RESULT: RESULT_FAIL, VALUE_DAYS: nil},
+		Details: map[string]string{"address": t.Address},
+	}
+
+	// chain is verified after handshake, so days are known even for bad chain
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", t.Address, &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		res.Error = "no certificates from peer"
+		return res
+	}
+
+	leaf, expiring := certs[0], certs[0]
+	for _, cert := range certs {
+		if cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+
+	res.Values[VALUE_DAYS] = float64(time.Until(expiring.NotAfter)) / float64(DAY)
+	res.Details["subject"] = leaf.Subject.CommonName
+	res.Details["issuer"] = leaf.Issuer.CommonName
+	res.Details["expires"] = fmt.Sprintf("%s (%s)", expiring.NotAfter.Format("2006-01-02 15:04"), expiring.Subject.CommonName)
+	if len(leaf.DNSNames) > 0 {
+		res.Details["names"] = strings.Join(leaf.DNSNames, ", ")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       t.ServerName,
+		Roots:         t.Roots,
+		Intermediates: intermediates,
+	})
+
+	switch err.(type) {
+	case nil:
+		res.Values[VALUE_RESULT] = RESULT_OK
+	case x509.HostnameError:
+		res.Error = "hostname mismatch: " + err.Error()
+	default:
+		res.Error = "chain error: " + err.Error()
+	}
+
+	return res
+}
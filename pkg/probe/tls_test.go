@@ -0,0 +1,134 @@
+package probe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+ * Generates certificate signed by parent (self-signed if parent is nil).
+ */
+func newCert(t *testing.T, name string, notAfter time.Time, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		template.DNSNames = []string{name}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+/*
+ * Starts https server with provided certificate.
+ */
+func newTlsServer(cert tls.Certificate) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	return server
+}
+
+func TestTlsTarget(t *testing.T) {
+	ca := newCert(t, "fuse test ca", time.Now().Add(365*DAY), nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	server := newTlsServer(newCert(t, "localhost", time.Now().Add(5*DAY+time.Hour), &ca))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	target := NewTlsTarget("localhost:" + port)
+	target.Roots = roots
+
+	res := target.Run(time.Second)
+	assert.Equal(t, RESULT_OK, res.Values[VALUE_RESULT], res.Error)
+	assert.InDelta(t, 5.0, res.Values[VALUE_DAYS], 0.1)
+	assert.Equal(t, "localhost", res.Details["subject"])
+	assert.Equal(t, "fuse test ca", res.Details["issuer"])
+	assert.Equal(t, "tls://localhost:"+port, target.GetQuery())
+
+	// default triggers warn about certificate which expires in 5 days
+	triggers := target.DefaultTriggers()
+	assert.Equal(t, "crit", triggers[VALUE_DAYS].Match(res.Values[VALUE_DAYS]))
+	assert.Equal(t, "good", triggers[VALUE_RESULT].Match(res.Values[VALUE_RESULT]))
+
+	target.ServerName = "api.example.com"
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Contains(t, res.Error, "hostname mismatch")
+	assert.InDelta(t, 5.0, res.Values[VALUE_DAYS], 0.1, "days are known for bad certificate")
+
+	// system roots don't trust test ca
+	target = NewTlsTarget("localhost:" + port)
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Contains(t, res.Error, "chain error")
+}
+
+func TestTlsTargetExpired(t *testing.T) {
+	ca := newCert(t, "fuse test ca", time.Now().Add(365*DAY), nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	server := newTlsServer(newCert(t, "localhost", time.Now().Add(-DAY), &ca))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	target := NewTlsTarget("localhost:" + port)
+	target.Roots = roots
+
+	res := target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Contains(t, res.Error, "chain error")
+	assert.True(t, res.Values[VALUE_DAYS].(float64) < 0)
+
+	// nothing is listening
+	server.Close()
+	res = target.Run(time.Second)
+	assert.Equal(t, RESULT_FAIL, res.Values[VALUE_RESULT])
+	assert.Nil(t, res.Values[VALUE_DAYS])
+	assert.NotEmpty(t, res.Error)
+}
+
+func TestTlsTargetAddress(t *testing.T) {
+	target := NewTlsTarget("example.com")
+	assert.Equal(t, "example.com:443", target.Address)
+	assert.Equal(t, "example.com", target.ServerName)
+
+	target = NewTlsTarget("mail.example.com:993")
+	assert.Equal(t, "mail.example.com:993", target.Address)
+	assert.Equal(t, "mail.example.com", target.ServerName)
+}
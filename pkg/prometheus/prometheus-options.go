@@ -0,0 +1,16 @@
+package prometheus
+
+// DTO for prometheus configuration
+type PrometheusOptions struct {
+	Address  string
+	Interval int
+	Alert    string
+}
+
+func DefaultPrometheusOptions() PrometheusOptions {
+	return PrometheusOptions{
+		Address:  "http://localhost:9090",
+		Interval: 5,
+		Alert:    "",
+	}
+}
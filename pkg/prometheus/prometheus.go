@@ -0,0 +1,151 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/models"
+	"github.com/parnurzeal/gorequest"
+
+	"fuse/pkg/influx"
+)
+
+/*
+ * Instant PromQL queries via http api of prometheus.
+ */
+type promQL struct {
+	options PrometheusOptions
+}
+
+/*
+ * Answer of /api/v1/query.
+ */
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+/*
+ * Element of instant vector.
+ */
+type sample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"` // [unix time, "value"]
+}
+
+/*
+ * Creates monitor which executes templates as PromQL queries. It uses templates,
+ * checks and triggers of influx monitor: every element of vector result is a
+ * series with labels as tags, scalar result is a single series without tags.
+ */
+func NewPrometheus(options PrometheusOptions) *influx.Influx {
+	return influx.NewQueryMonitor("prometheus", "", &promQL{options}, influx.InfluxOptions{
+		Address:  options.Address,
+		Interval: options.Interval,
+		Alert:    options.Alert,
+	})
+}
+
+/*
+ * QueryBackend interface implementation.
+ * Series have "time" and "value" columns.
+ */
+func (p *promQL) Query(query string) (*client.Result, error) {
+	answer := queryResponse{}
+
+	res, body, errs := gorequest.New().
+		Post(strings.TrimRight(p.options.Address, "/") + "/api/v1/query").
+		Type(gorequest.TypeForm).
+		Send(map[string]string{"query": query}).
+		Timeout(15 * time.Second).
+		End()
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	if err := json.Unmarshal([]byte(body), &answer); err != nil {
+		return nil, fmt.Errorf("prometheus responds with code %d", res.StatusCode)
+	}
+
+	if answer.Status != "success" {
+		return nil, fmt.Errorf("prometheus error (%s): %s", answer.ErrorType, answer.Error)
+	}
+
+	return convertResult(answer.Data.ResultType, answer.Data.Result)
+}
+
+func convertResult(resultType string, data json.RawMessage) (*client.Result, error) {
+	result := &client.Result{}
+
+	switch resultType {
+	case "scalar", "string":
+		var value []interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+
+		result.Series = append(result.Series, newRow(nil, value, resultType == "scalar"))
+	case "vector":
+		var samples []sample
+		if err := json.Unmarshal(data, &samples); err != nil {
+			return nil, err
+		}
+
+		for _, s := range samples {
+			result.Series = append(result.Series, newRow(s.Metric, s.Value, true))
+		}
+
+		// order of vector is not defined, but checks without "per series" take first series
+		sort.SliceStable(result.Series, func(i, j int) bool {
+			return influx.SeriesKey(result.Series[i].Tags) < influx.SeriesKey(result.Series[j].Tags)
+		})
+	default:
+		return nil, fmt.Errorf("result of type '%s' is not supported, use instant vector or scalar", resultType)
+	}
+
+	return result, nil
+}
+
+/*
+ * Converts [unix time, "value"] pair to series. Numbers are json.Number like in influx api.
+ */
+func newRow(labels map[string]string, pair []interface{}, number bool) models.Row {
+	row := models.Row{
+		Name:    labels["__name__"],
+		Tags:    make(map[string]string),
+		Columns: []string{"time", "value"},
+	}
+
+	for label, value := range labels {
+		if label != "__name__" {
+			row.Tags[label] = value
+		}
+	}
+
+	if len(pair) != 2 {
+		row.Values = [][]interface{}{{nil, nil}}
+		return row
+	}
+
+	at, value := pair[0], pair[1]
+	if unix, ok := at.(float64); ok {
+		at = time.Unix(0, int64(unix*1e9)).UTC().Format(time.RFC3339)
+	}
+
+	if str, ok := value.(string); ok && number {
+		value = json.Number(str)
+	}
+
+	row.Values = [][]interface{}{{at, value}}
+	return row
+}
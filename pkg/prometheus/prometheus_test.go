@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fuse/pkg/domain"
+	"fuse/pkg/influx"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+ * Fake prometheus which answers queries from map (unknown queries are bad requests).
+ */
+func prometheusApi(answers map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		data, ok := answers[r.FormValue("query")]
+		if r.URL.Path != "/api/v1/query" || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": "error", "errorType": "bad_data", "error": "parse error"}`))
+			return
+		}
+
+		w.Write([]byte(`{"status": "success", "data": ` + data + `}`))
+	}
+}
+
+func TestPrometheusResults(t *testing.T) {
+	server := httptest.NewServer(prometheusApi(map[string]string{
+		"rate": `{"resultType": "vector", "result": [
+			{"metric": {"instance": "web2:80", "job": "api"}, "value": [1546300800, "0.5"]},
+			{"metric": {"instance": "web1:80", "job": "api"}, "value": [1546300800, "0.01"]}
+		]}`,
+		"scalar(up)": `{"resultType": "scalar", "result": [1546300800, "3"]}`,
+		"up[5m]":     `{"resultType": "matrix", "result": []}`,
+	}))
+	defer server.Close()
+
+	p := &promQL{PrometheusOptions{Address: server.URL}}
+
+	res, err := p.Query("rate")
+	assert.NoError(t, err)
+	assert.Len(t, res.Series, 2)
+	assert.Equal(t, map[string]string{"instance": "web1:80", "job": "api"}, res.Series[0].Tags, "series are sorted by labels")
+	assert.Equal(t, []string{"time", "value"}, res.Series[0].Columns)
+	assert.Equal(t, [][]interface{}{{"2019-01-01T00:00:00Z", json.Number("0.01")}}, res.Series[0].Values)
+
+	res, err = p.Query("scalar(up)")
+	assert.NoError(t, err)
+	assert.Len(t, res.Series, 1)
+	assert.Len(t, res.Series[0].Tags, 0)
+	assert.Equal(t, json.Number("3"), res.Series[0].Values[0][1])
+
+	_, err = p.Query("up[5m]")
+	assert.EqualError(t, err, "result of type 'matrix' is not supported, use instant vector or scalar")
+
+	_, err = p.Query("up{")
+	assert.EqualError(t, err, "prometheus error (bad_data): parse error")
+}
+
+func TestPrometheusChecks(t *testing.T) {
+	server := httptest.NewServer(prometheusApi(map[string]string{
+		`count(up{job="api"} == 0)`: `{"resultType": "vector", "result": [{"metric": {}, "value": [1546300800, "2"]}]}`,
+	}))
+	defer server.Close()
+
+	options := DefaultPrometheusOptions()
+	options.Address = server.URL
+	p := NewPrometheus(options)
+	assert.Equal(t, "prometheus", p.GetName())
+
+	p.AddTemplate(&influx.Template{Name: "down", Body: `count(up{job="%job"} == 0)`, Args: []string{"job"}})
+	p.AddCheck(&influx.Check{Template: "down", Info: "targets down", Values: []string{"api"}, Trigger: domain.NewTrigger(nil)})
+
+	checks := p.GetChecks()
+	assert.Len(t, checks, 1)
+	assert.Equal(t, `count(up{job="api"} == 0)`, checks[0].GetQuery())
+
+	value, err := checks[0].Run(false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), value)
+}
@@ -0,0 +1,182 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// names of interactive buttons of incident message
+const (
+	ACTION_ACK     = "ack"
+	ACTION_SILENCE = "silence"
+	ACTION_RECHECK = "recheck"
+	ACTION_PREVIEW = "preview"
+)
+
+const SILENCE_DURATION = time.Hour
+
+/*
+ * Buttons of incident message; value of each button is report id.
+ */
+func makeButtons(incident *domain.Incident) []slack.BlockElement {
+	buttons := make([]slack.BlockElement, 0, 4)
+
+	if !incident.IsAcked() {
+		ack := slack.NewButtonBlockElement(ACTION_ACK, incident.Id, plainText("Acknowledge"))
+		ack.WithStyle(slack.StylePrimary)
+		buttons = append(buttons, ack)
+	}
+
+	return append(buttons,
+		slack.NewButtonBlockElement(ACTION_SILENCE, incident.Id, plainText("Silence 1h")),
+		slack.NewButtonBlockElement(ACTION_RECHECK, incident.Id, plainText("Re-check now")),
+		slack.NewButtonBlockElement(ACTION_PREVIEW, incident.Id, plainText("Show preview")),
+	)
+}
+
+func incidentStatus(incident *domain.Incident) string {
+	status := ""
+	if incident.IsAcked() {
+		status += fmt.Sprintf("acknowledged by %s\n", incident.GetAckedBy())
+	}
+
+	if until := incident.GetSilencedUntil(); until.After(time.Now()) {
+		status += fmt.Sprintf("silenced until %s\n", until.Format("15:04"))
+	}
+
+	return status
+}
+
+/*
+ * Handles clicks on buttons (block_actions and legacy interactive_message payloads).
+ * Slack expects quick empty answer, so results are posted to thread of incident.
+ */
+func (s *SlackClient) handleAction(w http.ResponseWriter, r *http.Request) {
+	if !s.readVerified(w, r) {
+		return
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &callback); err != nil {
+		log.WithError(err).Warn("slack: wrong payload of interactive action")
+		http.Error(w, "wrong payload", http.StatusBadRequest)
+		return
+	}
+
+	if !s.isAllowed(callback.User.ID, callback.Channel.ID) {
+		log.WithField("user", callback.User.ID).WithField("channel", callback.Channel.ID).Warn("slack: action from not allowed user or channel")
+		s.api.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText("You are not allowed to run fuse actions here", false))
+		return
+	}
+
+	// actions can take more time than slack waits for answer
+	go func() {
+		name, reportId := actionOf(&callback)
+		if err := s.ProcessAction(name, reportId, &callback); err != nil {
+			log.WithError(err).WithField("action", name).Error("slack: error during processing of action")
+		}
+	}()
+}
+
+/*
+ * Returns name of clicked button and report id from payload.
+ */
+func actionOf(callback *slack.InteractionCallback) (string, string) {
+	if actions := callback.ActionCallback.AttachmentActions; len(actions) > 0 {
+		return actions[0].Name, actions[0].Value
+	}
+
+	if actions := callback.ActionCallback.BlockActions; len(actions) > 0 {
+		return actions[0].ActionID, actions[0].Value
+	}
+
+	return "", ""
+}
+
+func (s *SlackClient) ProcessAction(name, reportId string, callback *slack.InteractionCallback) error {
+	user := "<@" + callback.User.ID + ">"
+
+	s.mu.RLock()
+	incident, ok := s.reports[reportId]
+	s.mu.RUnlock()
+
+	if !ok {
+		_, err := s.api.PostEphemeral(callback.Channel.ID, callback.User.ID, slack.MsgOptionText(fmt.Sprintf("Report `%s` is already resolved", reportId), false))
+		return err
+	}
+
+	// button can be clicked in copy of message (e.g. in thread), but the incident's message is updated
+	channel, ts := incident.GetAttr(ATTR_CHANNEL), incident.GetAttr(ATTR_TS)
+	if ts == "" {
+		channel, ts = callback.Channel.ID, callback.MessageTs
+		if ts == "" {
+			ts = callback.Message.Timestamp
+		}
+	}
+
+	log.WithFields(log.Fields{"action": name, "report": reportId, "user": callback.User.ID}).Info("slack: processing action")
+
+	switch name {
+	case ACTION_ACK:
+		if !incident.Ack(user) {
+			return nil // already acknowledged
+		}
+		return s.updateIncident(channel, ts, incident)
+
+	case ACTION_SILENCE:
+		incident.Silence(time.Now().Add(SILENCE_DURATION), user)
+		return s.updateIncident(channel, ts, incident)
+
+	case ACTION_RECHECK:
+		check, ok := s.checks.Find(reportId)
+		if !ok {
+			return s.reply(channel, ts, fmt.Sprintf("Check of report `%s` is not found", reportId))
+		}
+
+		value, err := check.Run(true)
+		if err != nil {
+			return s.reply(channel, ts, fmt.Sprintf("Re-check by %s failed: %s", user, err))
+		}
+		return s.reply(channel, ts, fmt.Sprintf("Re-check by %s: value is `%v`", user, value))
+
+	case ACTION_PREVIEW:
+		check, ok := s.checks.Find(reportId)
+		if !ok {
+			return s.reply(channel, ts, fmt.Sprintf("Check of report `%s` is not found", reportId))
+		}
+
+		preview := check.GetPreview()
+		if preview == nil {
+			return s.reply(channel, ts, "`no preview query available`")
+		}
+		return s.reply(channel, ts, "*preview query:*\n```"+preview.String()+"```")
+
+	default:
+		return fmt.Errorf("unknown action '%s'", name)
+	}
+}
+
+/*
+ * Re-renders message with current status of incident.
+ */
+func (s *SlackClient) updateIncident(channel, ts string, incident *domain.Incident) error {
+	_, _, _, err := s.api.UpdateMessage(channel, ts, s.messageToOptions(incident.GetMessage())...)
+	return err
+}
+
+func (s *SlackClient) reply(channel, ts, text string) error {
+	_, _, err := s.api.PostMessage(channel,
+		slack.MsgOptionUsername("fuse"),
+		slack.MsgOptionIconURL(s.iconUrl),
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(ts),
+	)
+	return err
+}
@@ -0,0 +1,184 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCheck struct {
+	reportId string
+	name     string
+	value    interface{}
+	fed      []interface{}
+	trigger  *domain.Trigger
+}
+
+func (c *fakeCheck) GetReportId() string         { return c.reportId }
+func (c *fakeCheck) GetName() string             { return c.name }
+func (c *fakeCheck) GetMonitor() string          { return "test" }
+func (c *fakeCheck) GetQuery() string            { return "select 1" }
+func (c *fakeCheck) GetTrigger() *domain.Trigger { return c.trigger }
+func (c *fakeCheck) GetPreview() *domain.Table {
+	return &domain.Table{Columns: []string{"a", "b"}, Rows: [][]string{{"1", "2"}}}
+}
+
+func (c *fakeCheck) Run(feed bool) (interface{}, error) {
+	if feed {
+		c.fed = append(c.fed, c.value)
+		if c.trigger != nil {
+			c.trigger.Touch(c.value)
+		}
+	}
+	return c.value, nil
+}
+
+type fakeSource []domain.Check
+
+func (s fakeSource) GetChecks() []domain.Check { return s }
+
+func newActionRequest(action, reportId string) *http.Request {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":    "block_actions",
+		"user":    map[string]string{"id": "U1"},
+		"channel": map[string]string{"id": "C123"},
+		"message": map[string]string{"type": "message", "ts": "1500000000.000100"},
+		"actions": []map[string]string{{"action_id": action, "block_id": reportId, "type": "button", "value": reportId}},
+	})
+
+	return newSignedRequest(testSigningSecret, "/actions", time.Now(), url.Values{"payload": {string(payload)}})
+}
+
+func TestSlackIncidentButtons(t *testing.T) {
+	s, server, _ := newSlackClientWithApi()
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{ReportId: "abc12", Level: domain.MSG_LVL_WARN})
+	s.Report(incident)
+
+	buttons := findButtons(s.messageToBlocks(incident.GetMessage()))
+	assert.Equal(t, []string{ACTION_ACK, ACTION_SILENCE, ACTION_RECHECK, ACTION_PREVIEW}, buttons)
+
+	// recovery message has no buttons
+	buttons = findButtons(s.messageToBlocks(domain.Message{ReportId: "abc12", Level: domain.MSG_LVL_GOOD}))
+	assert.Len(t, buttons, 0)
+
+	// acknowledged incident can't be acknowledged again
+	incident.Ack("test")
+	buttons = findButtons(s.messageToBlocks(incident.GetMessage()))
+	assert.Equal(t, []string{ACTION_SILENCE, ACTION_RECHECK, ACTION_PREVIEW}, buttons)
+
+	// without signing secret clicks can't be served
+	s.options.SigningSecret = ""
+	assert.Len(t, findButtons(s.messageToBlocks(incident.GetMessage())), 0)
+}
+
+func findButtons(blocks []slack.Block) []string {
+	actions := make([]string, 0)
+	for _, block := range blocks {
+		if block, ok := block.(*slack.ActionBlock); ok {
+			for _, element := range block.Elements.ElementSet {
+				actions = append(actions, element.(*slack.ButtonBlockElement).ActionID)
+			}
+		}
+	}
+	return actions
+}
+
+func TestSlackActionAckAndSilence(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{ReportId: "abc12", Level: domain.MSG_LVL_CRIT})
+	s.Report(incident)
+
+	w := serve(s, newActionRequest(ACTION_ACK, "abc12"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req := <-requests
+	assert.Equal(t, "/chat.update", req.Method)
+	assert.Equal(t, "1500000000.000100", req.Params.Get("ts"))
+	assert.Contains(t, req.Params.Get("blocks"), "acknowledged by \\u003c@U1\\u003e")
+	assert.NotContains(t, req.Params.Get("blocks"), "Acknowledge")
+	assert.Equal(t, "<@U1>", incident.GetAckedBy())
+
+	serve(s, newActionRequest(ACTION_SILENCE, "abc12"))
+	req = <-requests
+	assert.Equal(t, "/chat.update", req.Method)
+	assert.Contains(t, req.Params.Get("blocks"), "silenced until")
+	assert.True(t, incident.IsSilenced(time.Now()))
+	assert.False(t, incident.IsSilenced(time.Now().Add(SILENCE_DURATION)))
+}
+
+func TestSlackActionRecheckAndPreview(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+
+	check := &fakeCheck{reportId: "abc12", value: 42.0}
+	s.checks.AddSource(fakeSource{check})
+
+	s.Report(domain.NewIncidents().Open("abc12", domain.Message{ReportId: "abc12", Level: domain.MSG_LVL_CRIT}))
+
+	serve(s, newActionRequest(ACTION_RECHECK, "abc12"))
+	req := <-requests
+	assert.Equal(t, "/chat.postMessage", req.Method)
+	assert.Equal(t, "1500000000.000100", req.Params.Get("thread_ts"))
+	assert.Contains(t, req.Params.Get("text"), "value is `42`")
+	assert.Equal(t, []interface{}{42.0}, check.fed, "re-check must feed trigger")
+
+	serve(s, newActionRequest(ACTION_PREVIEW, "abc12"))
+	req = <-requests
+	assert.Contains(t, req.Params.Get("text"), "```a, b\n1, 2```")
+}
+
+func TestSlackActionInThreadReply(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{ReportId: "abc12", Level: domain.MSG_LVL_CRIT})
+	s.Report(incident)
+	assert.NoError(t, s.Crit(domain.Message{ReportId: "abc12"}))
+	<-requests
+
+	callback := &slack.InteractionCallback{MessageTs: "1500000000.000999"}
+	callback.User.ID = "U1"
+	callback.Channel.ID = "C999"
+
+	assert.NoError(t, s.ProcessAction(ACTION_ACK, "abc12", callback))
+	req := <-requests
+	assert.Equal(t, "/chat.update", req.Method)
+	assert.Equal(t, "C123", req.Params.Get("channel"), "message of incident is updated")
+	assert.Equal(t, "1500000000.000100", req.Params.Get("ts"))
+
+	assert.NoError(t, s.ProcessAction(ACTION_PREVIEW, "abc12", callback))
+	req = <-requests
+	assert.Equal(t, "C123", req.Params.Get("channel"))
+	assert.Equal(t, "1500000000.000100", req.Params.Get("thread_ts"), "reply goes to thread of incident")
+}
+
+func TestSlackActionResolvedIncident(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+
+	serve(s, newActionRequest(ACTION_ACK, "abc12"))
+	req := <-requests
+	assert.Equal(t, "/chat.postEphemeral", req.Method)
+	assert.Equal(t, "U1", req.Params.Get("user"))
+	assert.Contains(t, req.Params.Get("text"), "already resolved")
+}
+
+func TestSlackActionSignature(t *testing.T) {
+	s, server, _ := newSlackClientWithApi()
+	defer server.Close()
+	s.options.SigningSecret = "secret"
+
+	w := serve(s, newActionRequest(ACTION_ACK, "abc12"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,162 @@
+package slack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	MAX_SECTION_TEXT   = 3000 // slack limit for text of section block
+	MAX_SECTION_FIELDS = 10   // slack limit for fields of section block
+)
+
+/*
+ * Renders message as Block Kit layout: title, text by template of level, fields,
+ * status and buttons of active incident, context with source of message.
+ */
+func (s *SlackClient) messageToBlocks(msg domain.Message) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(markdown(s.renderTitle(msg)), nil, nil),
+	}
+
+	text := s.renderText(msg)
+	fields := makeFields(msg)
+	if text != "" || len(fields) > 0 {
+		var textObj *slack.TextBlockObject
+		if text != "" {
+			textObj = markdown(text)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(textObj, fields, nil))
+	}
+
+	s.mu.RLock()
+	incident, ok := s.reports[msg.ReportId]
+	s.mu.RUnlock()
+
+	if ok && msg.Level != domain.MSG_LVL_GOOD {
+		if status := incidentStatus(incident); status != "" {
+			blocks = append(blocks, slack.NewSectionBlock(markdown(status), nil, nil))
+		}
+
+		// clicks on buttons are delivered only to http listener (it needs signing secret)
+		if s.options.Connection == CONNECTION_HTTP && s.options.SigningSecret != "" {
+			blocks = append(blocks, slack.NewActionBlock(incident.Id, makeButtons(incident)...))
+		}
+	}
+
+	footer := []slack.MixedElement{}
+	if msg.IconUrl != "" {
+		footer = append(footer, slack.NewImageBlockElement(msg.IconUrl, msg.From))
+	}
+	footer = append(footer, markdown(fmt.Sprintf("%s | %s", msg.From, time.Now().Format("2006-01-02 15:04:05"))))
+
+	return append(blocks, slack.NewContextBlock("", footer...))
+}
+
+/*
+ * Bold title with level mark and mention of on-call person for bad messages.
+ */
+func (s *SlackClient) renderTitle(msg domain.Message) string {
+	title := fmt.Sprintf("%s *%s*", levelToEmoji(msg.Level), msg.Title)
+	if mention := s.mention(msg); mention != "" {
+		title = mention + " " + title
+	}
+	return title
+}
+
+/*
+ * Renders text of message with template of its level.
+ * Falls back to plain body if template can't be executed.
+ */
+func (s *SlackClient) renderText(msg domain.Message) string {
+	text, err := s.options.Templates.Render(msg)
+	if err != nil {
+		log.WithError(err).WithField("level", msg.LevelToStr()).Error("slack: can't render template of message")
+		text = msg.Body
+	}
+
+	if runes := []rune(text); len(runes) > MAX_SECTION_TEXT {
+		text = string(runes[:MAX_SECTION_TEXT-3]) + "..."
+	}
+
+	return text
+}
+
+/*
+ * Plain text for notifications and clients without blocks support.
+ */
+func (s *SlackClient) fallbackText(msg domain.Message) string {
+	text := fmt.Sprintf("[%s] %s", strings.ToUpper(msg.LevelToStr()), msg.Title)
+	if mention := s.mention(msg); mention != "" {
+		text = mention + " " + text
+	}
+	return text
+}
+
+/*
+ * Details and args of message as fields of section.
+ */
+func makeFields(msg domain.Message) []*slack.TextBlockObject {
+	if len(msg.Details) == 0 && len(msg.Args) == 0 {
+		return nil
+	}
+
+	kv := make(map[string]string)
+	for key, value := range msg.Details {
+		kv[key] = value
+	}
+
+	if len(msg.Args) > 0 {
+		args := make([]string, 0, len(msg.Args))
+		for key, value := range msg.Args {
+			args = append(args, fmt.Sprintf("%s = \"%v\"", key, value))
+		}
+		sort.Strings(args)
+		kv["args"] = strings.Join(args, "\n")
+	}
+
+	keys := make([]string, 0, len(kv))
+	for key := range kv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > MAX_SECTION_FIELDS {
+		keys = keys[:MAX_SECTION_FIELDS]
+	}
+
+	fields := make([]*slack.TextBlockObject, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, markdown(fmt.Sprintf("*%s*\n%s", key, kv[key])))
+	}
+
+	return fields
+}
+
+func markdown(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+}
+
+func plainText(text string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.PlainTextType, text, false, false)
+}
+
+func levelToEmoji(level int) string {
+	switch level {
+	case domain.MSG_LVL_GOOD:
+		return ":white_check_mark:"
+	case domain.MSG_LVL_WARN:
+		return ":warning:"
+	case domain.MSG_LVL_CRIT:
+		return ":red_circle:"
+	default:
+		return ":grey_question:"
+	}
+}
@@ -0,0 +1,220 @@
+package slack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/nlopes/slack"
+)
+
+/*
+ * Lists all checks with active state and counters:
+ *   checks [filter]
+ * Filter is matched (case insensitive) against name, monitor, report id and state of check.
+ */
+func (s *SlackClient) ProcessChecksCmd(options []string) *slack.Msg {
+	if params, ok := s.checkArgs(options, 0, 1, "/fuse checks [filter]"); !ok {
+		return params
+	}
+
+	filter := ""
+	if len(options) == 1 {
+		filter = strings.ToLower(options[0])
+	}
+
+	checks := s.checks.List()
+	sort.SliceStable(checks, func(i, j int) bool {
+		if checks[i].GetMonitor() != checks[j].GetMonitor() {
+			return checks[i].GetMonitor() < checks[j].GetMonitor()
+		}
+		return checks[i].GetName() < checks[j].GetName()
+	})
+
+	lines := make([]string, 0, len(checks))
+	for _, check := range checks {
+		state := stateOf(check)
+		haystack := strings.ToLower(strings.Join([]string{check.GetName(), check.GetMonitor(), check.GetReportId(), state}, " "))
+		if filter != "" && !strings.Contains(haystack, filter) {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s `%s` *%s* (%s) — %s", stateToEmoji(state), check.GetReportId(), check.GetName(), check.GetMonitor(), formatCounters(check.GetTrigger())))
+	}
+
+	params := s.makeDefaultSlackMsg()
+	switch {
+	case len(lines) > 0:
+		params.Text = strings.Join(lines, "\n")
+	case filter != "":
+		params.Text = fmt.Sprintf("No checks match `%s`", filter)
+	default:
+		params.Text = "No checks configured"
+	}
+
+	return params
+}
+
+/*
+ * Shows recent transitions of check's trigger:
+ *   history {report-id}
+ */
+func (s *SlackClient) ProcessHistoryCmd(options []string) *slack.Msg {
+	if params, ok := s.checkArgs(options, 1, 1, "/fuse history {report-id}"); !ok {
+		return params
+	}
+
+	params := s.makeDefaultSlackMsg()
+
+	check, ok := s.checks.Lookup(options[0])
+	if !ok {
+		params.Text = fmt.Sprintf("Can't find check: `%s`", options[0])
+		return params
+	}
+
+	trigger := check.GetTrigger()
+	if trigger == nil {
+		params.Text = fmt.Sprintf("Check *%s* is not started yet", check.GetName())
+		return params
+	}
+
+	transitions := trigger.GetTransitions()
+	if len(transitions) == 0 {
+		params.Text = fmt.Sprintf("*%s* has no transitions yet, current state is %s", check.GetName(), trigger.GetState())
+		return params
+	}
+
+	lines := []string{fmt.Sprintf("Recent transitions of *%s*:", check.GetName())}
+	for i := len(transitions) - 1; i >= 0; i-- {
+		tr := transitions[i]
+		lines = append(lines, fmt.Sprintf("%s %s → %s %s (value `%v`)", tr.Time.Format("2006-01-02 15:04:05"), tr.From, stateToEmoji(tr.To), tr.To, tr.Value))
+	}
+
+	params.Text = strings.Join(lines, "\n")
+	return params
+}
+
+/*
+ * Shows last value and rendered query of check:
+ *   value {report-id or name}
+ */
+func (s *SlackClient) ProcessValueCmd(options []string) *slack.Msg {
+	if len(options) == 0 {
+		params, _ := s.checkArgs(options, 1, 1, "/fuse value {report-id or check name}")
+		return params
+	}
+
+	params := s.makeDefaultSlackMsg()
+
+	// names of checks can contain spaces
+	name := strings.Join(options, " ")
+	check, ok := s.checks.Lookup(name)
+	if !ok {
+		params.Text = fmt.Sprintf("Can't find check: `%s`", name)
+		return params
+	}
+
+	lines := []string{fmt.Sprintf("*%s* (%s, `%s`)", check.GetName(), check.GetMonitor(), check.GetReportId())}
+
+	if trigger := check.GetTrigger(); trigger != nil {
+		value, at := trigger.GetLastValue()
+		if at.IsZero() {
+			lines = append(lines, "no value received yet")
+		} else {
+			lines = append(lines, fmt.Sprintf("last value `%v` at %s (%s ago), state %s", value, at.Format("2006-01-02 15:04:05"), time.Since(at).Round(time.Second), trigger.GetState()))
+		}
+	} else {
+		lines = append(lines, "check is not started yet")
+	}
+
+	if query := check.GetQuery(); query != "" {
+		lines = append(lines, "```"+query+"```")
+	}
+
+	params.Text = strings.Join(lines, "\n")
+	return params
+}
+
+/*
+ * Runs check out-of-band:
+ *   run [feed] {report-id or name}
+ */
+func (s *SlackClient) ProcessRunCmd(options []string) *slack.Msg {
+	feed := len(options) > 1 && options[0] == "feed"
+	if feed {
+		options = options[1:]
+	}
+
+	if len(options) == 0 {
+		params, _ := s.checkArgs(options, 1, 1, "/fuse run [feed] {report-id or check name}")
+		return params
+	}
+
+	params := s.makeDefaultSlackMsg()
+
+	name := strings.Join(options, " ")
+	check, ok := s.checks.Lookup(name)
+	if !ok {
+		params.Text = fmt.Sprintf("Can't find check: `%s`", name)
+		return params
+	}
+
+	result, err := domain.RunCheck(check, feed)
+	if err != nil {
+		params.Text = fmt.Sprintf("Run of *%s* failed: %s", check.GetName(), err)
+		return params
+	}
+
+	lines := []string{fmt.Sprintf("*%s* (%s, `%s`): value `%v`", result.Name, result.Monitor, result.ReportId, result.Value)}
+	switch {
+	case result.State == "":
+		lines = append(lines, "check is not started yet, value is not compared with states")
+	case result.Match == "":
+		lines = append(lines, fmt.Sprintf("value matches no state, active state is %s", result.State))
+	default:
+		lines = append(lines, fmt.Sprintf("value matches %s %s, active state is %s", stateToEmoji(result.Match), result.Match, result.State))
+	}
+
+	if result.Fed {
+		lines = append(lines, "value was passed to trigger")
+	}
+
+	params.Text = strings.Join(lines, "\n")
+	return params
+}
+
+func stateOf(check domain.Check) string {
+	if trigger := check.GetTrigger(); trigger != nil {
+		return trigger.GetState()
+	}
+	return ""
+}
+
+/*
+ * Formats counters of trigger's states like "good 0/5, warn 2/5, crit 0/10".
+ */
+func formatCounters(trigger *domain.Trigger) string {
+	if trigger == nil {
+		return "not started yet"
+	}
+
+	counters := make([]string, 0)
+	for _, c := range trigger.GetCounters() {
+		counters = append(counters, fmt.Sprintf("%s %d/%d", c.Name, c.Counter, c.Cycles))
+	}
+
+	text := strings.Join(counters, ", ")
+	if value, at := trigger.GetLastValue(); !at.IsZero() {
+		text += fmt.Sprintf(", last value `%v`", value)
+	}
+	return text
+}
+
+func stateToEmoji(state string) string {
+	msg := domain.Message{}
+	msg.ParseLevel(state)
+	return levelToEmoji(msg.Level)
+}
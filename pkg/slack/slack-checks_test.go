@@ -0,0 +1,110 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTrigger() *domain.Trigger {
+	trigger := domain.NewTrigger(func(*domain.State, interface{}) error { return nil })
+	trigger.AddState(&domain.State{Name: "good", Cycles: 1, Value: float64(0), Operator: "="})
+	trigger.AddState(&domain.State{Name: "crit", Cycles: 2, Value: float64(0), Operator: ">"})
+	return trigger
+}
+
+func newChecksSlackClient() (*SlackClient, *fakeCheck) {
+	s := newTestSlackClient()
+
+	errors := &fakeCheck{reportId: "abc12", name: "api errors", trigger: newTestTrigger()}
+	s.checks.AddSource(fakeSource{
+		errors,
+		&fakeCheck{reportId: "def34", name: "grafana", trigger: newTestTrigger()},
+		&fakeCheck{reportId: "fff00", name: "not started"},
+	})
+
+	return s, errors
+}
+
+func TestSlackChecksCmd(t *testing.T) {
+	s, errors := newChecksSlackClient()
+	errors.trigger.Touch(float64(3))
+	errors.trigger.Touch(float64(4))
+
+	text := s.ProcessCmd("checks").Text
+	assert.Contains(t, text, ":red_circle: `abc12` *api errors* (test) — good 0/1, crit 2/2, last value `4`")
+	assert.Contains(t, text, ":white_check_mark: `def34` *grafana* (test) — good 0/1, crit 0/2")
+	assert.Contains(t, text, "`fff00` *not started* (test) — not started yet")
+
+	text = s.ProcessCmd("checks  CRIT").Text
+	assert.Contains(t, text, "abc12")
+	assert.NotContains(t, text, "def34")
+
+	assert.Equal(t, "No checks match `missing`", s.ProcessCmd("checks missing").Text)
+	assert.Equal(t, "Usage: `/fuse checks [filter]`", s.ProcessCmd("checks a b").Text)
+}
+
+func TestSlackHistoryCmd(t *testing.T) {
+	s, errors := newChecksSlackClient()
+
+	assert.Contains(t, s.ProcessCmd("history abc12").Text, "has no transitions yet, current state is good")
+
+	errors.trigger.Touch(float64(3))
+	errors.trigger.Touch(float64(4))
+	errors.trigger.Touch(float64(0))
+
+	text := s.ProcessCmd("history abc12").Text
+	assert.Contains(t, text, "Recent transitions of *api errors*")
+	assert.Contains(t, text, "crit → :white_check_mark: good (value `0`)")
+	assert.Contains(t, text, "good → :red_circle: crit (value `4`)")
+	assert.True(t, strings.Index(text, "→ :white_check_mark:") < strings.Index(text, "→ :red_circle:"), "newest transition goes first")
+
+	assert.Equal(t, "Usage: `/fuse history {report-id}`", s.ProcessCmd("history").Text)
+	assert.Equal(t, "Can't find check: `zzz`", s.ProcessCmd("history zzz").Text)
+	assert.Contains(t, s.ProcessCmd("history fff00").Text, "is not started yet")
+}
+
+func TestSlackValueCmd(t *testing.T) {
+	s, errors := newChecksSlackClient()
+
+	assert.Contains(t, s.ProcessCmd("value abc12").Text, "no value received yet")
+
+	errors.trigger.Touch(float64(3))
+	text := s.ProcessCmd("value API errors").Text
+	assert.Contains(t, text, "*api errors* (test, `abc12`)")
+	assert.Contains(t, text, "last value `3`")
+	assert.Contains(t, text, "```select 1```")
+
+	assert.Equal(t, "Usage: `/fuse value {report-id or check name}`", s.ProcessCmd("value").Text)
+}
+
+func TestSlackCmdArguments(t *testing.T) {
+	s := newTestSlackClient()
+
+	assert.Equal(t, "Usage: `/fuse show {report-id}`", s.ProcessCmd("show").Text, "show without argument must not panic")
+	assert.Equal(t, "Can't find report with id: `abc12`", s.ProcessCmd("  show   abc12 ").Text)
+	assert.Contains(t, s.ProcessCmd("").Text, "Usage:")
+	assert.Contains(t, s.ProcessCmd("unknown").Text, "Unknown command `unknown`")
+}
+
+func TestSlackRunCmd(t *testing.T) {
+	s, errors := newChecksSlackClient()
+	errors.value = float64(5)
+
+	text := s.ProcessCmd("run api errors").Text
+	assert.Contains(t, text, "*api errors* (test, `abc12`): value `5`")
+	assert.Contains(t, text, "value matches :red_circle: crit, active state is good")
+	assert.NotContains(t, text, "passed to trigger")
+	assert.Len(t, errors.fed, 0)
+
+	text = s.ProcessCmd("run feed abc12").Text
+	assert.Contains(t, text, "value was passed to trigger")
+	assert.Equal(t, []interface{}{float64(5)}, errors.fed)
+
+	assert.Contains(t, s.ProcessCmd("run fff00").Text, "check is not started yet")
+	assert.Equal(t, "Usage: `/fuse run [feed] {report-id or check name}`", s.ProcessCmd("run").Text)
+	assert.Equal(t, "Can't find check: `feed`", s.ProcessCmd("run feed").Text)
+}
@@ -1,23 +1,47 @@
 package slack
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
 )
 
 /*
- * Implements HTTP callback server for slash-command in slack.
+ * Implements HTTP callback server for slash-command and interactive buttons in slack.
  */
-func (s *SlackClient) ConfigureHTTP() {
-	// TODO: configure http via DI; replace http with iris?
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		cmd := r.FormValue("text")
+func (s *SlackClient) ConfigureHTTP(mux *http.ServeMux, basePath string) {
+	if s.options.Connection == CONNECTION_RTM {
+		log.Info("slack: commands are received via RTM connection, http callbacks are disabled")
+		return
+	}
+
+	if s.options.SigningSecret == "" {
+		log.Error("slack: 'signing_secret' is not set, http callbacks are disabled")
+		return
+	}
 
-		params := s.ProcessCmd(cmd)
+	mux.HandleFunc(basePath+"/actions", s.handleAction)
+	mux.HandleFunc(basePath+"/", func(w http.ResponseWriter, r *http.Request) {
+		if !s.readVerified(w, r) {
+			return
+		}
+
+		var params *slack.Msg
+		if s.isAllowed(r.FormValue("user_id"), r.FormValue("channel_id")) {
+			params = s.ProcessCmd(r.FormValue("text"))
+		} else {
+			log.WithField("user", r.FormValue("user_id")).WithField("channel", r.FormValue("channel_id")).Warn("slack: command from not allowed user or channel")
+			params = s.makeDefaultSlackMsg()
+			params.Text = "You are not allowed to run fuse commands here"
+		}
 
 		// output json answer
 		w.Header().Set("Content-Type", "application/json")
@@ -25,17 +49,54 @@ func (s *SlackClient) ConfigureHTTP() {
 	})
 }
 
+/*
+ * Checks signature of request from slack and restores body for parsing form values.
+ * Writes error response and returns false if request must be rejected.
+ */
+func (s *SlackClient) readVerified(w http.ResponseWriter, r *http.Request) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	if err := s.verifyRequest(r.Header, body, time.Now()); err != nil {
+		log.WithError(err).WithField("remote", r.RemoteAddr).Warn("slack: rejecting request")
+		http.Error(w, "wrong signature", http.StatusUnauthorized)
+		return false
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return true
+}
+
 func (s *SlackClient) ProcessCmd(cmd string) *slack.Msg {
-	arr := strings.Split(cmd, " ")
-	switch arr[0] {
+	args := strings.Fields(cmd)
+	if len(args) == 0 {
+		return s.ProcessHelpCmd()
+	}
+
+	switch strings.ToLower(args[0]) {
 	case "help":
 		return s.ProcessHelpCmd()
 	case "list":
-		return s.ProcessListCmd(arr[1:])
+		return s.ProcessListCmd(args[1:])
 	case "show":
-		return s.ProcessShowCmd(arr[1:])
+		return s.ProcessShowCmd(args[1:])
+	case "checks":
+		return s.ProcessChecksCmd(args[1:])
+	case "history":
+		return s.ProcessHistoryCmd(args[1:])
+	case "value":
+		return s.ProcessValueCmd(args[1:])
+	case "run":
+		return s.ProcessRunCmd(args[1:])
+	case "oncall":
+		return s.ProcessOncallCmd(args[1:])
 	default:
-		return s.ProcessHelpCmd()
+		params := s.ProcessHelpCmd()
+		params.Text = fmt.Sprintf("Unknown command `%s`\n", args[0]) + params.Text
+		return params
 	}
 }
 
@@ -45,37 +106,69 @@ func (s *SlackClient) ProcessHelpCmd() *slack.Msg {
 	params.Text = "Usage:\n" +
 		"`/fuse help` — this help\n" +
 		"`/fuse list` — list all active reports\n" +
-		"`/fuse show {report-id}` — show one particular report from list"
+		"`/fuse show {report-id}` — show one particular report from list\n" +
+		"`/fuse checks [filter]` — list all checks with current state and counters\n" +
+		"`/fuse history {report-id}` — show recent state transitions of check\n" +
+		"`/fuse value {report-id or check name}` — show last value and query of check\n" +
+		"`/fuse run [feed] {report-id or check name}` — run check now and show matching state (`feed` passes value to trigger)\n" +
+		"`/fuse oncall` — show who is on call now\n" +
+		"`/fuse oncall {schedule} set {member} [duration]` — override on-call person (until next handoff by default)\n" +
+		"`/fuse oncall {schedule} clear` — remove active overrides"
 
 	return params
 }
 
+/*
+ * Returns usage of command if it has wrong amount of arguments.
+ */
+func (s *SlackClient) checkArgs(options []string, min, max int, usage string) (*slack.Msg, bool) {
+	if len(options) >= min && len(options) <= max {
+		return nil, true
+	}
+
+	params := s.makeDefaultSlackMsg()
+	params.Text = fmt.Sprintf("Usage: `%s`", usage)
+	return params, false
+}
+
 func (s *SlackClient) ProcessListCmd(options []string) *slack.Msg {
 	params := s.makeDefaultSlackMsg()
 
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	if len(s.reports) == 0 {
 		params.Text = "No issue reports! All works!"
 		return params
 	}
 
-	attachments := make([]slack.Attachment, 0, len(s.reports))
+	ids := make([]string, 0, len(s.reports))
+	for id := range s.reports {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
 
-	for id, report := range s.reports {
-		attachments = append(attachments, slack.Attachment{
-			Text:       fmt.Sprintf("`%s` — %s\n", id, report.Title),
-			Color:      s.levelToColor(report.Level),
-			MarkdownIn: []string{"text"},
-		})
+	lines := make([]string, 0, len(ids))
+	for _, id := range ids {
+		report := s.reports[id].GetMessage()
+		lines = append(lines, fmt.Sprintf("%s `%s` — %s", levelToEmoji(report.Level), id, report.Title))
 	}
 
-	params.Attachments = attachments
+	params.Text = strings.Join(lines, "\n")
+	params.Blocks.BlockSet = []slack.Block{slack.NewSectionBlock(markdown(params.Text), nil, nil)}
 
 	return params
 }
 
 func (s *SlackClient) ProcessShowCmd(options []string) *slack.Msg {
+	if params, ok := s.checkArgs(options, 1, 1, "/fuse show {report-id}"); !ok {
+		return params
+	}
 	id := options[0]
-	report, ok := s.reports[id]
+
+	s.mu.RLock()
+	incident, ok := s.reports[id]
+	s.mu.RUnlock()
 
 	params := s.makeDefaultSlackMsg()
 
@@ -84,7 +177,9 @@ func (s *SlackClient) ProcessShowCmd(options []string) *slack.Msg {
 		return params
 	}
 
-	params.Attachments = s.messageToAttachments(report)
+	msg := incident.GetMessage()
+	params.Text = s.fallbackText(msg)
+	params.Blocks.BlockSet = s.messageToBlocks(msg)
 	return params
 }
 
@@ -0,0 +1,96 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/nlopes/slack"
+)
+
+/*
+ * Shows or overrides on-call schedules:
+ *   oncall
+ *   oncall {schedule} set {member} [duration]
+ *   oncall {schedule} clear
+ */
+func (s *SlackClient) ProcessOncallCmd(options []string) *slack.Msg {
+	params := s.makeDefaultSlackMsg()
+
+	if len(options) == 0 {
+		return s.listSchedules(params)
+	}
+
+	schedule, ok := s.schedules.Get(options[0])
+	if !ok {
+		params.Text = fmt.Sprintf("Can't find on-call schedule: `%s`", options[0])
+		return params
+	}
+
+	now := time.Now()
+	switch {
+	case len(options) >= 3 && options[1] == "set":
+		// members are contacts for alerts (e.g. phones for twilio), so only known ones are accepted
+		if !schedule.HasMember(options[2]) {
+			params.Text = fmt.Sprintf("`%s` is not a member of *%s*, use one of: `%s`", options[2], schedule.Name, strings.Join(schedule.Members, "`, `"))
+			return params
+		}
+
+		to := schedule.NextHandoff(now)
+		if len(options) >= 4 {
+			duration, err := time.ParseDuration(options[3])
+			if err != nil || duration <= 0 {
+				params.Text = fmt.Sprintf("Wrong duration `%s`, use format like `2h` or `30m`", options[3])
+				return params
+			}
+			to = now.Add(duration)
+		}
+
+		schedule.AddOverride(options[2], now, to)
+		params.Text = fmt.Sprintf("`%s` is on call for *%s* until %s", options[2], schedule.Name, formatScheduleTime(schedule, to))
+	case len(options) == 2 && options[1] == "clear":
+		schedule.ClearOverrides(now)
+		params.Text = fmt.Sprintf("Overrides removed, `%s` is on call for *%s*", schedule.OnCall(now), schedule.Name)
+	default:
+		params.Text = "Usage: `/fuse oncall {schedule} set {member} [duration]` or `/fuse oncall {schedule} clear`"
+	}
+
+	return params
+}
+
+func (s *SlackClient) listSchedules(params *slack.Msg) *slack.Msg {
+	schedules := s.schedules.List()
+	if len(schedules) == 0 {
+		params.Text = "No on-call schedules configured"
+		return params
+	}
+
+	now := time.Now()
+	attachments := make([]slack.Attachment, 0, len(schedules))
+	for _, schedule := range schedules {
+		text := fmt.Sprintf("*%s* (%s): `%s` is on call, next handoff at %s",
+			schedule.Name, schedule.Rotation, schedule.OnCall(now), formatScheduleTime(schedule, schedule.NextHandoff(now)))
+
+		overrides := make([]string, 0)
+		for _, o := range schedule.GetOverrides(now) {
+			overrides = append(overrides, fmt.Sprintf("`%s` from %s to %s", o.Member, formatScheduleTime(schedule, o.From), formatScheduleTime(schedule, o.To)))
+		}
+		if len(overrides) > 0 {
+			text += "\noverrides: " + strings.Join(overrides, ", ")
+		}
+
+		attachments = append(attachments, slack.Attachment{
+			Text:       text,
+			MarkdownIn: []string{"text"},
+		})
+	}
+
+	params.Attachments = attachments
+	return params
+}
+
+func formatScheduleTime(schedule *domain.Schedule, t time.Time) string {
+	return t.In(schedule.Location).Format("Mon 2006-01-02 15:04 MST")
+}
@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackOncallCommand(t *testing.T) {
+	s := newTestSlackClient()
+	s.options.Mention = "oncall:primary"
+
+	schedule := domain.NewSchedule("primary")
+	schedule.Members = []string{"U1", "U2"}
+	s.schedules.Add(schedule)
+
+	msg := s.ProcessCmd("oncall")
+	assert.Len(t, msg.Attachments, 1)
+	assert.Contains(t, msg.Attachments[0].Text, "`U1` is on call")
+
+	msg = s.ProcessCmd("oncall primary set U2 2h")
+	assert.Contains(t, msg.Text, "`U2` is on call for *primary*")
+	assert.Equal(t, "U2", schedule.OnCall(time.Now()))
+	assert.Equal(t, "<@U2>", s.mention(domain.Message{Level: domain.MSG_LVL_CRIT}), "mention must follow on-call override")
+
+	msg = s.ProcessCmd("oncall primary set U2 soon")
+	assert.Contains(t, msg.Text, "Wrong duration")
+
+	msg = s.ProcessCmd("oncall primary set +15550000000")
+	assert.Equal(t, "`+15550000000` is not a member of *primary*, use one of: `U1`, `U2`", msg.Text)
+	assert.Equal(t, "U2", schedule.OnCall(time.Now()), "unknown member must not be set on call")
+
+	msg = s.ProcessCmd("oncall primary clear")
+	assert.Equal(t, "U1", schedule.OnCall(time.Now()))
+
+	msg = s.ProcessCmd("oncall unknown")
+	assert.Contains(t, msg.Text, "Can't find on-call schedule")
+}
+
+func TestSlackMention(t *testing.T) {
+	s := newTestSlackClient()
+	assert.Equal(t, "", s.mention(domain.Message{Level: domain.MSG_LVL_CRIT}))
+
+	s.options.Mention = "U3"
+	assert.Equal(t, "<@U3>", s.mention(domain.Message{Level: domain.MSG_LVL_WARN}))
+	assert.Equal(t, "", s.mention(domain.Message{Level: domain.MSG_LVL_GOOD}))
+
+	s.options.Mention = "<!here>"
+	assert.Equal(t, "<!here>", s.mention(domain.Message{Level: domain.MSG_LVL_CRIT}))
+}
@@ -0,0 +1,48 @@
+package slack
+
+import (
+	"time"
+
+	"fuse/pkg/domain"
+)
+
+// how slack delivers commands and button clicks to fuse
+const (
+	CONNECTION_HTTP = "http" // inbound requests to http listener (slash-commands, buttons)
+	CONNECTION_RTM  = "rtm"  // outbound RTM connection (mentions and direct messages to bot)
+)
+
+// default per-level templates for text of message (slack mrkdwn)
+const (
+	TEMPLATE_GOOD = "{{.Body}}"
+	TEMPLATE_BAD  = "*{{.LevelToStr | upper}}:* {{.Body}}" +
+		"{{if .Query}}\n```{{.Query}}```{{end}}" +
+		"{{if .Preview}}\n*preview query:*\n```{{.Preview}}```{{else if .Query}}\n`no preview query available`{{end}}"
+)
+
+// DTO for slack configuration
+type SlackOptions struct {
+	Channel string
+	Token   string
+	ApiUrl  string // custom url of slack api (must end with "/")
+	IconUrl string
+	Mention string // user id (or "oncall:{name}") to mention in warn/crit messages
+
+	Connection      string          // CONNECTION_HTTP or CONNECTION_RTM
+	SigningSecret   string          // secret for verifying X-Slack-Signature of slash-commands
+	ReplayWindow    time.Duration   // max allowed age of signed request
+	AllowedUsers    map[string]bool // if not empty only these user ids can run commands
+	AllowedChannels map[string]bool // if not empty commands are accepted only from these channels
+
+	Templates *domain.MessageTemplates // per-level templates for text of message
+}
+
+func DefaultSlackOptions() SlackOptions {
+	return SlackOptions{
+		Connection:      CONNECTION_HTTP,
+		ReplayWindow:    5 * time.Minute,
+		AllowedUsers:    make(map[string]bool),
+		AllowedChannels: make(map[string]bool),
+		Templates:       domain.NewMessageTemplates(TEMPLATE_GOOD, TEMPLATE_BAD, TEMPLATE_BAD),
+	}
+}
@@ -0,0 +1,131 @@
+package slack
+
+import (
+	"strings"
+
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ * Connector interface implementation.
+ * Opens outbound RTM connection if "connection = rtm" is configured, so commands
+ * work without inbound http requests from slack.
+ */
+func (s *SlackClient) Connect() error {
+	if s.options.Connection != CONNECTION_RTM {
+		return nil
+	}
+
+	log.Info("slack: opening RTM connection")
+
+	s.rtm = s.api.NewRTM()
+	s.stop = make(chan struct{})
+
+	go s.rtm.ManageConnection()
+	go s.handleRTM(s.rtm.IncomingEvents, s.stop)
+	return nil
+}
+
+/*
+ * Connector interface implementation.
+ */
+func (s *SlackClient) Disconnect() error {
+	if s.rtm == nil {
+		return nil
+	}
+
+	log.Info("slack: closing RTM connection")
+	close(s.stop)
+
+	// Disconnect blocks while RTM is reconnecting, so don't wait for it
+	go s.rtm.Disconnect()
+	return nil
+}
+
+func (s *SlackClient) handleRTM(events chan slack.RTMEvent, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-events:
+			switch ev := event.Data.(type) {
+			case *slack.ConnectedEvent:
+				log.WithField("bot", ev.Info.User.ID).Info("slack: RTM connection established")
+				s.setBotId(ev.Info.User.ID)
+			case *slack.MessageEvent:
+				if err := s.processMessage(ev); err != nil {
+					log.WithError(err).Error("slack: error during answering RTM message")
+				}
+			case *slack.InvalidAuthEvent:
+				log.Error("slack: RTM connection is rejected, check 'token' option")
+				return
+			case *slack.RTMError:
+				log.WithError(ev).Warn("slack: RTM error")
+			}
+		}
+	}
+}
+
+/*
+ * Runs command from message which mentions bot ("@fuse list") or from direct message ("list").
+ * Answer is posted to the same channel (to the same thread for messages in thread).
+ */
+func (s *SlackClient) processMessage(ev *slack.MessageEvent) error {
+	botId := s.getBotId()
+
+	// skip edits, joins, own and other bot's messages
+	if ev.SubType != "" || ev.BotID != "" || ev.User == "" || ev.User == botId {
+		return nil
+	}
+
+	mention := "<@" + botId + ">"
+	text := strings.TrimSpace(ev.Text)
+
+	switch {
+	case botId != "" && strings.HasPrefix(text, mention):
+		text = strings.TrimSpace(strings.TrimPrefix(text, mention))
+	case strings.HasPrefix(ev.Channel, "D"):
+		// direct message to bot
+	default:
+		return nil
+	}
+
+	var params *slack.Msg
+	if s.isAllowed(ev.User, ev.Channel) {
+		params = s.ProcessCmd(text)
+	} else {
+		log.WithField("user", ev.User).WithField("channel", ev.Channel).Warn("slack: command from not allowed user or channel")
+		params = s.makeDefaultSlackMsg()
+		params.Text = "You are not allowed to run fuse commands here"
+	}
+
+	options := []slack.MsgOption{
+		slack.MsgOptionUsername(params.Username),
+		slack.MsgOptionIconURL(s.iconUrl),
+		slack.MsgOptionText(params.Text, false),
+	}
+
+	if len(params.Blocks.BlockSet) > 0 {
+		options = append(options, slack.MsgOptionBlocks(params.Blocks.BlockSet...))
+	}
+
+	if ev.ThreadTimestamp != "" {
+		options = append(options, slack.MsgOptionTS(ev.ThreadTimestamp))
+	}
+
+	_, _, err := s.api.PostMessage(ev.Channel, options...)
+	return err
+}
+
+func (s *SlackClient) setBotId(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.botId = id
+}
+
+func (s *SlackClient) getBotId() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.botId
+}
@@ -0,0 +1,70 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackRTMCommands(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+
+	events := make(chan slack.RTMEvent, 5)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.handleRTM(events, stop)
+		close(done)
+	}()
+
+	events <- slack.RTMEvent{Type: "connected", Data: &slack.ConnectedEvent{Info: &slack.Info{User: &slack.UserDetails{ID: "UBOT"}}}}
+
+	// mention of bot in channel
+	events <- slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{Msg: slack.Msg{User: "U1", Channel: "C1", Text: "<@UBOT> list"}}}
+	req := <-requests
+	assert.Equal(t, "/chat.postMessage", req.Method)
+	assert.Equal(t, "C1", req.Params.Get("channel"))
+	assert.Equal(t, "No issue reports! All works!", req.Params.Get("text"))
+
+	// direct message in thread
+	events <- slack.RTMEvent{Type: "message", Data: &slack.MessageEvent{Msg: slack.Msg{User: "U1", Channel: "D1", Text: "help", ThreadTimestamp: "1500000000.000100"}}}
+	req = <-requests
+	assert.Equal(t, "D1", req.Params.Get("channel"))
+	assert.Equal(t, "1500000000.000100", req.Params.Get("thread_ts"))
+	assert.Contains(t, req.Params.Get("text"), "Usage:")
+
+	close(stop)
+	<-done
+	assert.Len(t, requests, 0)
+}
+
+func TestSlackRTMIgnoresOtherMessages(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+	s.setBotId("UBOT")
+
+	for _, msg := range []slack.Msg{
+		{User: "U1", Channel: "C1", Text: "just talking about fuse"},
+		{User: "UBOT", Channel: "D1", Text: "No issue reports! All works!"},
+		{User: "U1", Channel: "C1", Text: "<@UBOT> list", SubType: "message_changed"},
+		{Channel: "C1", Text: "<@UBOT> list", BotID: "B1"},
+	} {
+		assert.NoError(t, s.processMessage(&slack.MessageEvent{Msg: msg}))
+	}
+	assert.Len(t, requests, 0)
+
+	s.options.AllowedUsers = ParseIdList("U2")
+	assert.NoError(t, s.processMessage(&slack.MessageEvent{Msg: slack.Msg{User: "U1", Channel: "C1", Text: "<@UBOT> list"}}))
+	req := <-requests
+	assert.Equal(t, "You are not allowed to run fuse commands here", req.Params.Get("text"))
+}
+
+func TestSlackRTMDisablesHttpCallbacks(t *testing.T) {
+	s := newTestSlackClient()
+	s.options.Connection = CONNECTION_RTM
+
+	w := serve(s, newActionRequest(ACTION_ACK, "abc12"))
+	assert.Equal(t, 404, w.Code)
+}
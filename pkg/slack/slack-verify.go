@@ -0,0 +1,82 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+ * Checks that request body was signed by slack with configured signing secret.
+ * See: https://api.slack.com/authentication/verifying-requests-from-slack
+ */
+func (s *SlackClient) verifyRequest(header http.Header, body []byte, now time.Time) error {
+	if s.options.SigningSecret == "" {
+		return fmt.Errorf("signing secret is not configured")
+	}
+
+	signature := header.Get("X-Slack-Signature")
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+
+	if signature == "" || timestamp == "" {
+		return fmt.Errorf("missing signature headers")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("wrong timestamp format: %s", err)
+	}
+
+	// protection against replaying of captured requests
+	age := now.Sub(time.Unix(ts, 0))
+	if age > s.options.ReplayWindow || age < -s.options.ReplayWindow {
+		return fmt.Errorf("request timestamp is outside of replay window (%s)", age)
+	}
+
+	expected := signRequest(s.options.SigningSecret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("wrong signature")
+	}
+
+	return nil
+}
+
+/*
+ * Checks user and channel of slash-command against configured white lists.
+ */
+func (s *SlackClient) isAllowed(userId, channelId string) bool {
+	if len(s.options.AllowedUsers) > 0 && !s.options.AllowedUsers[userId] {
+		return false
+	}
+
+	if len(s.options.AllowedChannels) > 0 && !s.options.AllowedChannels[channelId] {
+		return false
+	}
+
+	return true
+}
+
+func signRequest(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("v0:" + timestamp + ":"))
+	h.Write(body)
+	return "v0=" + hex.EncodeToString(h.Sum(nil))
+}
+
+/*
+ * Parses comma separated list of ids into set.
+ */
+func ParseIdList(list string) map[string]bool {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,115 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func newTestSlackClient() *SlackClient {
+	options := DefaultSlackOptions()
+	options.Channel = "#test"
+	options.Token = "xoxb-test"
+	options.SigningSecret = testSigningSecret
+	return NewSlackClient(options, domain.NewSchedules(), domain.NewChecks())
+}
+
+func newSignedRequest(secret, path string, ts time.Time, form url.Values) *http.Request {
+	body := form.Encode()
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+
+	r := httptest.NewRequest("POST", path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", signRequest(secret, timestamp, []byte(body)))
+	return r
+}
+
+func serve(s *SlackClient, r *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	s.ConfigureHTTP(mux, "")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestSlackVerifyKnownSignature(t *testing.T) {
+	// example from slack documentation
+	s := newTestSlackClient()
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", "1531420618")
+	header.Set("X-Slack-Signature", "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503")
+
+	assert.NoError(t, s.verifyRequest(header, body, time.Unix(1531420618, 0)))
+	assert.Error(t, s.verifyRequest(header, append(body, 'x'), time.Unix(1531420618, 0)), "modified body must be rejected")
+	assert.Error(t, s.verifyRequest(header, body, time.Unix(1531420618, 0).Add(6*time.Minute)), "old request must be rejected")
+}
+
+func TestSlackCommandSignature(t *testing.T) {
+	s := newTestSlackClient()
+	form := url.Values{"text": {"help"}, "user_id": {"U1"}, "channel_id": {"C1"}}
+
+	w := serve(s, newSignedRequest(s.options.SigningSecret, "/", time.Now(), form))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Usage")
+
+	w = serve(s, newSignedRequest("wrong-secret", "/", time.Now(), form))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(s, newSignedRequest(s.options.SigningSecret, "/", time.Now().Add(-time.Hour), form))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "replayed request must be rejected")
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = serve(s, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "unsigned request must be rejected")
+}
+
+func TestSlackCommandAllowedUsersAndChannels(t *testing.T) {
+	s := newTestSlackClient()
+	s.options.AllowedUsers = ParseIdList("U1, U2")
+	s.options.AllowedChannels = ParseIdList("C1")
+
+	matrix := map[[2]string]bool{
+		{"U1", "C1"}: true,
+		{"U2", "C1"}: true,
+		{"U3", "C1"}: false,
+		{"U1", "C2"}: false,
+	}
+
+	for ids, allowed := range matrix {
+		form := url.Values{"text": {"help"}, "user_id": {ids[0]}, "channel_id": {ids[1]}}
+		w := serve(s, newSignedRequest(s.options.SigningSecret, "/", time.Now(), form))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equalf(t, allowed, strings.Contains(w.Body.String(), "Usage"), "wrong access for user %s in channel %s", ids[0], ids[1])
+	}
+}
+
+func TestSlackCallbacksRequireSecret(t *testing.T) {
+	s := newTestSlackClient()
+	s.options.SigningSecret = ""
+	form := url.Values{"text": {"help"}, "user_id": {"U1"}, "channel_id": {"C1"}}
+
+	w := serve(s, newSignedRequest("", "/", time.Now(), form))
+	assert.Equal(t, http.StatusNotFound, w.Code, "callbacks are not served without signing secret")
+
+	body := []byte(form.Encode())
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	header.Set("X-Slack-Signature", signRequest("", header.Get("X-Slack-Request-Timestamp"), body))
+	assert.Error(t, s.verifyRequest(header, body, time.Now()), "empty secret never verifies")
+}
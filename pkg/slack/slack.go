@@ -1,11 +1,8 @@
 package slack
 
 import (
-	//"io"
-
-	"fmt"
-	"sort"
-	"time"
+	"strings"
+	"sync"
 
 	"fuse/pkg/domain"
 
@@ -15,24 +12,46 @@ import (
 
 var slackApi *slack.Client
 
+// incident's attributes with first message of incident
+const (
+	ATTR_CHANNEL = "slack.channel"
+	ATTR_TS      = "slack.ts"
+)
+
 type SlackClient struct {
 	BotUsername string
 	IconURL     string
 
-	api     *slack.Client
-	channel string
-	iconUrl string
-	reports map[string]domain.Message
+	api       *slack.Client
+	channel   string
+	iconUrl   string
+	reports   map[string]*domain.Incident
+	mu        sync.RWMutex // guards reports and botId
+	options   SlackOptions
+	schedules *domain.Schedules // on-call schedules for mentions and "/fuse oncall"
+	checks    *domain.Checks    // checks of monitors for interactive buttons
+
+	rtm   *slack.RTM    // outbound connection (only for CONNECTION_RTM)
+	stop  chan struct{} // closed on Disconnect
+	botId string        // user id of bot received after RTM connection
 }
 
-func NewSlackClient(channel string, token string, iconUrl string) *SlackClient {
-	slackApi := slack.New(token)
+func NewSlackClient(options SlackOptions, schedules *domain.Schedules, checks *domain.Checks) *SlackClient {
+	apiOptions := make([]slack.Option, 0)
+	if options.ApiUrl != "" {
+		apiOptions = append(apiOptions, slack.OptionAPIURL(options.ApiUrl))
+	}
+
+	slackApi := slack.New(options.Token, apiOptions...)
 
 	return &SlackClient{
-		api:     slackApi,
-		channel: channel,
-		iconUrl: iconUrl,
-		reports: make(map[string]domain.Message),
+		api:       slackApi,
+		channel:   options.Channel,
+		iconUrl:   options.IconUrl,
+		reports:   make(map[string]*domain.Incident),
+		options:   options,
+		schedules: schedules,
+		checks:    checks,
 	}
 }
 
@@ -42,105 +61,94 @@ func (s *SlackClient) GetName() string {
 
 func (s *SlackClient) Good(msg domain.Message) error {
 	msg.Level = domain.MSG_LVL_GOOD
-	options := s.messageToOptions(msg)
-
-	_, _, err := s.api.PostMessage(s.channel, options...)
-	return err
+	return s.post(msg)
 }
 
 func (s *SlackClient) Warn(msg domain.Message) error {
 	msg.Level = domain.MSG_LVL_WARN
-	options := s.messageToOptions(msg)
-
-	_, _, err := s.api.PostMessage(s.channel, options...)
-	return err
+	return s.post(msg)
 }
 
 func (s *SlackClient) Crit(msg domain.Message) error {
 	msg.Level = domain.MSG_LVL_CRIT
-	options := s.messageToOptions(msg)
-
-	_, _, err := s.api.PostMessage(s.channel, options...)
-	return err
+	return s.post(msg)
 }
 
-func (s *SlackClient) messageToOptions(msg domain.Message) []slack.MsgOption {
-	opts := []slack.MsgOption{
-		slack.MsgOptionUsername("fuse"),
-		slack.MsgOptionIconURL(s.iconUrl),
-		slack.MsgOptionAttachments(s.messageToAttachments(msg)...),
-	}
+/*
+ * Posts message to channel. Messages of the same incident are grouped into thread:
+ * first message starts the thread and always reflects current level of incident.
+ */
+func (s *SlackClient) post(msg domain.Message) error {
+	s.mu.RLock()
+	incident, ok := s.reports[msg.ReportId]
+	s.mu.RUnlock()
 
-	return opts
-}
+	options := s.messageToOptions(msg)
 
-func (s *SlackClient) messageToAttachments(msg domain.Message) []slack.Attachment {
-	return []slack.Attachment{
-		slack.Attachment{
-			Color:      s.levelToColor(msg.Level),
-			Title:      msg.Title,
-			Text:       msg.Body,
-			MarkdownIn: []string{"text"},
-			Fields:     s.makeFields(msg),
-			Footer:     fmt.Sprintf("%s | %s", msg.From, time.Now().Format("2006-01-02 15:04:05")),
-			FooterIcon: msg.IconUrl,
-		},
+	if !ok {
+		_, _, err := s.api.PostMessage(s.channel, options...)
+		return err
 	}
-}
 
-func (s *SlackClient) makeFields(msg domain.Message) []slack.AttachmentField {
-	if len(msg.Details) == 0 && len(msg.Args) == 0 {
+	channel, ts := incident.GetAttr(ATTR_CHANNEL), incident.GetAttr(ATTR_TS)
+	if ts == "" {
+		channel, ts, err := s.api.PostMessage(s.channel, options...)
+		if err != nil {
+			return err
+		}
+
+		incident.SetAttr(ATTR_CHANNEL, channel)
+		incident.SetAttr(ATTR_TS, ts)
 		return nil
 	}
 
-	fields := make([]slack.AttachmentField, 0)
-
-	keys := make([]string, 0)
-	kv := make(map[string]interface{})
-	for key, value := range msg.Details {
-		keys = append(keys, key)
-		kv[key] = value
+	// reply in thread of incident ...
+	if _, _, err := s.api.PostMessage(channel, append(options, slack.MsgOptionTS(ts))...); err != nil {
+		return err
 	}
 
-	args := ""
-	for key, value := range msg.Args {
-		args += fmt.Sprintf("%s = \"%s\" \n", key, value)
+	// ... and update the first message
+	_, _, _, err := s.api.UpdateMessage(channel, ts, s.messageToOptions(msg)...)
+	return err
+}
+
+func (s *SlackClient) messageToOptions(msg domain.Message) []slack.MsgOption {
+	return []slack.MsgOption{
+		slack.MsgOptionUsername("fuse"),
+		slack.MsgOptionIconURL(s.iconUrl),
+		slack.MsgOptionText(s.fallbackText(msg), false),
+		slack.MsgOptionBlocks(s.messageToBlocks(msg)...),
 	}
-	keys = append(keys, "args")
-	kv["args"] = args
+}
 
-	sort.Strings(keys)
+/*
+ * Returns mention of configured user (or current on-call member) for bad messages.
+ */
+func (s *SlackClient) mention(msg domain.Message) string {
+	if s.options.Mention == "" || msg.Level == domain.MSG_LVL_GOOD {
+		return ""
+	}
 
-	for _, name := range keys {
-		fields = append(fields, slack.AttachmentField{
-			Title: name,
-			Value: fmt.Sprintf("%v", kv[name]),
-			Short: true,
-		})
+	user := s.schedules.Resolve(s.options.Mention)
+	if user == "" || strings.HasPrefix(user, "<") {
+		return user
 	}
 
-	return fields
+	return "<@" + user + ">"
 }
 
-func (s *SlackClient) Report(reportId string, msg domain.Message) error {
-	s.reports[reportId] = msg
-	return nil
-}
+func (s *SlackClient) Report(incident *domain.Incident) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-func (s *SlackClient) Resolve(reportId string) error {
-	delete(s.reports, reportId)
+	s.reports[incident.Id] = incident
 	return nil
 }
 
-func (s *SlackClient) levelToColor(level int) string {
-	switch level {
-	case domain.MSG_LVL_GOOD:
-		return "good"
-	case domain.MSG_LVL_WARN:
-		return "warning"
-	case domain.MSG_LVL_CRIT:
-		return "danger"
-	default:
-		return ""
-	}
+func (s *SlackClient) Resolve(incident *domain.Incident) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.reports, incident.Id)
+	return nil
 }
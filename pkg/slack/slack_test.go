@@ -0,0 +1,112 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type slackRequest struct {
+	Method string
+	Params url.Values
+}
+
+/*
+ * Creates client connected to local http server which records all requests to slack api.
+ */
+func newSlackClientWithApi() (*SlackClient, *httptest.Server, chan slackRequest) {
+	requests := make(chan slackRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests <- slackRequest{r.URL.Path, r.PostForm}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": "C123", "ts": "1500000000.000100"})
+	}))
+
+	options := DefaultSlackOptions()
+	options.Channel = "#test"
+	options.Token = "xoxb-test"
+	options.ApiUrl = server.URL + "/"
+	options.SigningSecret = testSigningSecret
+
+	return NewSlackClient(options, domain.NewSchedules(), domain.NewChecks()), server, requests
+}
+
+/*
+ * Returns text of first block (title of message).
+ */
+func titleBlock(t *testing.T, params url.Values) string {
+	var blocks []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(params.Get("blocks")), &blocks))
+	return blocks[0]["text"].(map[string]interface{})["text"].(string)
+}
+
+func TestSlackIncidentThread(t *testing.T) {
+	s, server, requests := newSlackClientWithApi()
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{})
+	s.Report(incident)
+
+	// first message of incident starts thread
+	assert.NoError(t, s.Warn(domain.Message{ReportId: "abc12", Title: "warn"}))
+	req := <-requests
+	assert.Equal(t, "/chat.postMessage", req.Method)
+	assert.Equal(t, "#test", req.Params.Get("channel"))
+	assert.Equal(t, "", req.Params.Get("thread_ts"))
+	assert.Equal(t, "1500000000.000100", incident.GetAttr(ATTR_TS))
+
+	// next one is reply in thread + update of first message
+	assert.NoError(t, s.Crit(domain.Message{ReportId: "abc12", Title: "crit"}))
+	req = <-requests
+	assert.Equal(t, "/chat.postMessage", req.Method)
+	assert.Equal(t, "C123", req.Params.Get("channel"))
+	assert.Equal(t, "1500000000.000100", req.Params.Get("thread_ts"))
+
+	req = <-requests
+	assert.Equal(t, "/chat.update", req.Method)
+	assert.Equal(t, "1500000000.000100", req.Params.Get("ts"))
+	assert.Equal(t, ":red_circle: *crit*", titleBlock(t, req.Params))
+
+	// resolved incident is shown green
+	assert.NoError(t, s.Good(domain.Message{ReportId: "abc12", Title: "good"}))
+	<-requests
+	req = <-requests
+	assert.Equal(t, "/chat.update", req.Method)
+	assert.Equal(t, ":white_check_mark: *good*", titleBlock(t, req.Params))
+
+	// messages without incident are posted as usual
+	s.Resolve(incident)
+	assert.NoError(t, s.Good(domain.Message{Title: "restarted"}))
+	req = <-requests
+	assert.Equal(t, "", req.Params.Get("thread_ts"))
+	assert.Len(t, requests, 0)
+}
+
+func TestSlackMessageTemplates(t *testing.T) {
+	s, server, _ := newSlackClientWithApi()
+	defer server.Close()
+
+	msg := domain.Message{
+		Level: domain.MSG_LVL_CRIT,
+		Title: "QUERY: errors in CRIT state",
+		Body:  "Query has bad value for more than 1m0s.",
+		Query: "select count(*) from http",
+	}
+
+	// default template formats structured data of message
+	assert.Equal(t, "*CRIT:* Query has bad value for more than 1m0s.\n```select count(*) from http```\n`no preview query available`", s.renderText(msg))
+
+	msg.Preview = &domain.Table{Columns: []string{"id"}, Rows: [][]string{{"42"}}}
+	assert.Contains(t, s.renderText(msg), "*preview query:*\n```id\n42```")
+
+	assert.NoError(t, s.options.Templates.Set(domain.MSG_LVL_CRIT, "{{.Title}} :fire:"))
+	assert.Equal(t, "QUERY: errors in CRIT state :fire:", s.renderText(msg))
+}
@@ -0,0 +1,22 @@
+package sql
+
+// DTO for sql database configuration
+type SqlOptions struct {
+	Name     string // name of database in config, e.g. "billing"
+	Driver   string // name of database/sql driver: "postgres", "mysql", etc
+	Dsn      string
+	Timeout  int // timeout of query in seconds
+	Interval int
+	Alert    string
+}
+
+func DefaultSqlOptions() SqlOptions {
+	return SqlOptions{
+		Name:     "",
+		Driver:   "",
+		Dsn:      "",
+		Timeout:  10,
+		Interval: 60,
+		Alert:    "",
+	}
+}
@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/models"
+	log "github.com/sirupsen/logrus"
+
+	"fuse/pkg/influx"
+
+	// drivers of databases
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+var numberRe = regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?$`)
+
+/*
+ * Queries via database/sql driver. Every query is executed in its own
+ * read-only transaction which is rolled back after reading of rows.
+ */
+type sqlQuery struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+/*
+ * Creates monitor which executes templates as sql queries. It uses templates,
+ * checks and triggers of influx monitor: result of query is a single series
+ * without tags, checks take value of first row.
+ */
+func NewSql(options SqlOptions) *influx.Influx {
+	db, err := sql.Open(options.Driver, options.Dsn)
+	if err != nil {
+		log.Fatalf("sql '%s': %s", options.Name, err)
+	}
+
+	// queries are executed one by one by monitor's loop, chat-ops can add one more
+	db.SetMaxOpenConns(2)
+
+	backend := &sqlQuery{db, time.Duration(options.Timeout) * time.Second}
+	return influx.NewQueryMonitor("sql:"+options.Name, "", backend, influx.InfluxOptions{
+		Interval: options.Interval,
+		Alert:    options.Alert,
+	})
+}
+
+/*
+ * QueryBackend interface implementation.
+ * Numbers (including numeric strings, e.g. DECIMAL) are json.Number like in influx api,
+ * NULL is nil, other values are strings.
+ */
+func (q *sqlQuery) Query(query string) (*client.Result, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+	defer cancel()
+
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // transaction is read-only, nothing to commit
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	row := models.Row{
+		Tags:    make(map[string]string),
+		Columns: columns,
+		Values:  make([][]interface{}, 0),
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for n := range values {
+			pointers[n] = &values[n]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		for n, value := range values {
+			values[n] = convertValue(value)
+		}
+
+		row.Values = append(row.Values, values)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &client.Result{Series: []models.Row{row}}, nil
+}
+
+func convertValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	case []byte:
+		return convertValue(string(v))
+	case string:
+		// drivers return DECIMAL and all values of text protocol (e.g. mysql) as strings
+		if numberRe.MatchString(v) {
+			return json.Number(v)
+		}
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
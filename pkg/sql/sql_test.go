@@ -0,0 +1,149 @@
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+
+	"fuse/pkg/domain"
+	"fuse/pkg/influx"
+)
+
+/*
+ * Creates sqlite database with payments in temporary directory.
+ */
+func createBillingDb(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "fuse-sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsn := filepath.Join(dir, "billing.db")
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE payments (id INTEGER PRIMARY KEY, status TEXT, amount DECIMAL(10, 2), updated_at DATETIME);
+		INSERT INTO payments (status, amount, updated_at) VALUES
+			('pending', '12.50', datetime('now', '-2 hours')),
+			('pending', '7.25', datetime('now', '-3 hours')),
+			('done', '100', datetime('now')),
+			('failed', NULL, datetime('now'));
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dsn, func() { os.RemoveAll(dir) }
+}
+
+func newSqlQuery(t *testing.T, dsn string, timeout time.Duration) *sqlQuery {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sqlQuery{db, timeout}
+}
+
+func TestSqlQuery(t *testing.T) {
+	dsn, cleanup := createBillingDb(t)
+	defer cleanup()
+
+	q := newSqlQuery(t, dsn, time.Second)
+
+	res, err := q.Query("SELECT status, count(*) AS amount, sum(amount) AS total FROM payments GROUP BY status ORDER BY status")
+	assert.NoError(t, err)
+	assert.Len(t, res.Series, 1)
+	assert.Empty(t, res.Series[0].Tags)
+	assert.Equal(t, []string{"status", "amount", "total"}, res.Series[0].Columns)
+	assert.Equal(t, [][]interface{}{
+		{"done", json.Number("1"), json.Number("100")},
+		{"failed", json.Number("1"), nil},
+		{"pending", json.Number("2"), json.Number("19.75")},
+	}, res.Series[0].Values)
+
+	res, err = q.Query("SELECT id FROM payments WHERE status = 'unknown'")
+	assert.NoError(t, err)
+	assert.Len(t, res.Series[0].Values, 0)
+
+	_, err = q.Query("SELECT * FROM refunds")
+	assert.EqualError(t, err, "no such table: refunds")
+}
+
+func TestSqlQueryReadOnly(t *testing.T) {
+	dsn, cleanup := createBillingDb(t)
+	defer cleanup()
+
+	q := newSqlQuery(t, dsn, time.Second)
+
+	// sqlite ignores read-only flag of transactions, but changes are rolled back anyway
+	q.Query("UPDATE payments SET status = 'done'")
+
+	res, err := q.Query("SELECT count(*) FROM payments WHERE status = 'pending'")
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("2"), res.Series[0].Values[0][0])
+}
+
+func TestSqlQueryTimeout(t *testing.T) {
+	dsn, cleanup := createBillingDb(t)
+	defer cleanup()
+
+	q := newSqlQuery(t, dsn, 100*time.Millisecond)
+
+	start := time.Now()
+	_, err := q.Query(`
+		WITH RECURSIVE counter(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM counter)
+		SELECT count(*) FROM counter`)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestSqlChecks(t *testing.T) {
+	dsn, cleanup := createBillingDb(t)
+	defer cleanup()
+
+	options := DefaultSqlOptions()
+	options.Name, options.Driver, options.Dsn = "billing", "sqlite3", dsn
+
+	m := NewSql(options)
+	assert.Equal(t, "sql:billing", m.GetName())
+
+	m.AddTemplate(&influx.Template{
+		Name:    "stuck",
+		Body:    "SELECT count(*) AS stuck, sum(amount) AS total FROM payments WHERE status = '%status' AND updated_at < datetime('now', '-1 hour')",
+		Preview: "SELECT id, amount FROM payments WHERE status = '%status' ORDER BY id",
+		Args:    []string{"status"},
+	})
+	m.AddCheck(&influx.Check{Template: "stuck", Info: "stuck payments", Values: []string{"pending"}, Trigger: domain.NewTrigger(nil)})
+	m.AddCheck(&influx.Check{Template: "stuck", Info: "stuck total", Values: []string{"pending"}, Column: "total", Trigger: domain.NewTrigger(nil)})
+	m.AddCheck(&influx.Check{Template: "stuck", Info: "stuck refunds", Values: []string{"refund"}, Column: "total", Trigger: domain.NewTrigger(nil)})
+
+	checks := m.GetChecks()
+	assert.Len(t, checks, 3)
+
+	value, err := checks[0].Run(false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), value, "first column without time")
+
+	value, err = checks[1].Run(false)
+	assert.NoError(t, err)
+	assert.Equal(t, 19.75, value)
+
+	value, err = checks[2].Run(false)
+	assert.NoError(t, err)
+	assert.Nil(t, value, "NULL is nil")
+
+	preview := checks[0].GetPreview()
+	assert.Equal(t, []string{"id", "amount"}, preview.Columns)
+	assert.Len(t, preview.Rows, 2)
+}
@@ -0,0 +1,81 @@
+package twilio
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const gatherHint = "Press 1 to acknowledge. Press 2 to escalate to the next person."
+
+/*
+ * Handles keypad input from <Gather>: 1 - acknowledge, 2 - escalate.
+ */
+func (t *TwilioClient) handleGather(w http.ResponseWriter, r *http.Request) {
+	reportId := r.FormValue("report")
+	digits := r.FormValue("Digits")
+	phone := r.FormValue("To") // phone of person who answered the call
+
+	log.WithFields(log.Fields{"report": reportId, "digits": digits, "phone": phone}).Info("twilio: keypad input")
+
+	t.mu.RLock()
+	incident, ok := t.reports[reportId]
+	t.mu.RUnlock()
+
+	var twiml TwiML
+	switch {
+	case !ok:
+		twiml.Say = t.say("Incident is already resolved. Goodbye.")
+	case digits == "1":
+		if incident.Ack("phone " + phone) {
+			twiml.Say = t.say("Incident acknowledged. Goodbye.")
+		} else {
+			twiml.Say = t.say("Incident was already acknowledged by " + incident.GetAckedBy() + ". Goodbye.")
+		}
+	case digits == "2":
+		next := t.nextPhone(phone)
+		if next == "" {
+			twiml.Say = t.say("There is nobody to escalate to. Goodbye.")
+			break
+		}
+
+		incident.AddEvent("escalated by phone %s to %s", phone, next)
+		if err := t.dial(reportId, next); err != nil {
+			twiml.Say = t.say("Escalation failed. Goodbye.")
+			break
+		}
+		twiml.Say = t.say("Escalated to the next person. Goodbye.")
+	default:
+		twiml.Gather = &TwiMLGather{
+			Action:    t.callbackUrl("/gather", reportId),
+			Method:    "POST",
+			NumDigits: 1,
+			Timeout:   10,
+			Say:       t.say("Unknown option. " + gatherHint),
+		}
+	}
+
+	xml, err := marshalTwiML(twiml)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(xml)
+}
+
+/*
+ * Returns phone which follows provided one in escalation chain
+ * (or empty string if it is the last one).
+ * Chain can contain "oncall:{name}" items, they are resolved to current phones.
+ */
+func (t *TwilioClient) nextPhone(phone string) string {
+	chain := append([]string{t.phoneTo}, t.options.EscalateTo...)
+	for i, p := range chain[:len(chain)-1] {
+		if t.schedules.Resolve(p) == phone {
+			return t.schedules.Resolve(chain[i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package twilio
+
+import (
+	"time"
+
+	"fuse/pkg/domain"
+)
+
+// default template for text of SMS (the same for all levels)
+const TEMPLATE_SMS = "[{{.LevelToStr | upper}}] {{.Title}}" +
+	"{{with index .Details \"value\"}}\nvalue: {{.}}{{end}}" +
+	"{{if .Body}}\n{{.Body}}{{end}}" +
+	"{{if .ReportId}}\nreport: {{.ReportId}}{{end}}"
+
+const (
+	MODE_CALL = "call"
+	MODE_SMS  = "sms"
+	MODE_NONE = "none"
+)
+
+// DTO for twilio configuration
+type TwilioOptions struct {
+	PhoneTo    string   // make call to this phone (or current member of "oncall:{name}" schedule)
+	PhoneFrom  string   // .. from this phone
+	EscalateTo []string // phones to call one by one after pressing 2 or if nobody answers
+
+	Retries    int           // how many times to repeat not answered call before calling next phone
+	RetryDelay time.Duration // pause between not answered call and next attempt
+
+	Token  string // api token
+	Sid    string // api sid
+	ApiUrl string // base url of twilio api
+
+	// how to alert about message of each level: MODE_CALL, MODE_SMS or MODE_NONE
+	ModeGood string
+	ModeWarn string
+	ModeCrit string
+
+	TwimlUrl string // public url which returns TwiML document
+
+	Voice    string // voice for <Say> (e.g. "alice", "Polly.Joanna")
+	Language string // language for <Say> (e.g. "en-US", "ru-RU")
+	PlayUrl  string // optional mp3 to play if incident data is not available
+
+	Templates *domain.MessageTemplates // per-level templates for text of SMS
+}
+
+func DefaultTwilioOptions() TwilioOptions {
+	return TwilioOptions{
+		EscalateTo: make([]string, 0),
+		Retries:    2,
+		RetryDelay: 30 * time.Second,
+		ApiUrl:     "https://api.twilio.com",
+		ModeGood:   MODE_NONE,
+		ModeWarn:   MODE_NONE,
+		ModeCrit:   MODE_CALL,
+		Voice:      "alice",
+		Language:   "en-US",
+		PlayUrl:    "",
+		Templates:  domain.NewMessageTemplates(TEMPLATE_SMS, TEMPLATE_SMS, TEMPLATE_SMS),
+	}
+}
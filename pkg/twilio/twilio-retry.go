@@ -0,0 +1,110 @@
+package twilio
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// state of calling chain for one report
+type callState struct {
+	phone   string // phone which is called now
+	attempt int    // number of attempt for this phone (starting from 1)
+}
+
+/*
+ * Starts calling chain for report from provided phone.
+ * If incident is not acknowledged during call it will be retried and then next phones
+ * from 'escalate_to' will be called.
+ */
+func (t *TwilioClient) dial(reportId string, phone string) error {
+	if reportId != "" {
+		t.mu.Lock()
+		t.calls[reportId] = &callState{phone: phone, attempt: 1}
+		t.mu.Unlock()
+	}
+
+	return t.callAttempt(reportId, phone, 1)
+}
+
+func (t *TwilioClient) callAttempt(reportId string, phone string, attempt int) error {
+	t.mu.RLock()
+	incident, ok := t.reports[reportId]
+	t.mu.RUnlock()
+
+	if ok {
+		incident.AddEvent("call attempt %d to %s", attempt, phone)
+	}
+
+	return t.call(phone, reportId)
+}
+
+/*
+ * Handles call status callback from twilio: retries call or moves to next phone until incident
+ * is acknowledged (by keypad, see handleGather) or silenced. Completed call is not enough,
+ * it can be answered by voicemail.
+ */
+func (t *TwilioClient) handleStatus(w http.ResponseWriter, r *http.Request) {
+	reportId := r.FormValue("report")
+	status := r.FormValue("CallStatus")
+	phone := r.FormValue("To")
+
+	log.WithFields(log.Fields{"report": reportId, "status": status, "phone": phone}).Info("twilio: call status")
+
+	// twilio ignores answer, but it expects successful status
+	w.WriteHeader(http.StatusNoContent)
+
+	t.mu.Lock()
+	incident, ok := t.reports[reportId]
+	state, calling := t.calls[reportId]
+	if !ok || !calling || state.phone != phone {
+		t.mu.Unlock()
+		return // incident is resolved or status is for outdated call
+	}
+
+	incident.AddEvent("call attempt %d to %s: %s", state.attempt, phone, status)
+
+	if incident.IsAcked() || incident.IsSilenced(time.Now()) {
+		delete(t.calls, reportId) // somebody took the incident, stop calling
+		t.mu.Unlock()
+		return
+	}
+
+	if state.attempt <= t.options.Retries {
+		state.attempt++
+	} else if next := t.nextPhone(phone); next != "" {
+		state.phone, state.attempt = next, 1
+	} else {
+		delete(t.calls, reportId)
+		t.mu.Unlock()
+
+		incident.AddEvent("nobody answered the call")
+		log.WithField("report", reportId).Error("twilio: nobody answered the call")
+		return
+	}
+
+	next, attempt := state.phone, state.attempt
+	t.mu.Unlock()
+
+	time.AfterFunc(t.options.RetryDelay, func() {
+		if incident.IsAcked() || incident.IsSilenced(time.Now()) {
+			return
+		}
+
+		// incident can be resolved or chain can be restarted by dial() during delay (new state)
+		t.mu.RLock()
+		_, reported := t.reports[reportId]
+		current, calling := t.calls[reportId]
+		outdated := !reported || !calling || current != state || current.phone != next || current.attempt != attempt
+		t.mu.RUnlock()
+
+		if outdated {
+			return
+		}
+
+		if err := t.callAttempt(reportId, next, attempt); err != nil {
+			log.WithError(err).WithField("report", reportId).Error("twilio: error during retrying call")
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package twilio
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fuse/pkg/domain"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const SMS_MAX_LENGTH = 320 // two segments
+
+/*
+ * Renders message for SMS with template of its level (truncated to SMS_MAX_LENGTH).
+ */
+func (t *TwilioClient) formatSms(msg domain.Message) string {
+	text, err := t.options.Templates.Render(msg)
+	if err != nil {
+		log.WithError(err).WithField("level", msg.LevelToStr()).Error("twilio: can't render template of message")
+		text = fmt.Sprintf("[%s] %s", strings.ToUpper(msg.LevelToStr()), msg.Title)
+	}
+
+	if runes := []rune(text); len(runes) > SMS_MAX_LENGTH {
+		text = string(runes[:SMS_MAX_LENGTH-3]) + "..."
+	}
+
+	return text
+}
+
+/*
+ * Returns text for speech synthesis: title, level and duration of incident.
+ */
+func describeIncident(incident *domain.Incident) string {
+	msg := incident.GetMessage()
+
+	return fmt.Sprintf("Fuse alert. Level %s. %s. Lasting for %s.",
+		speakLevel(msg.Level),
+		msg.Title,
+		speakDuration(incident.GetDuration()),
+	)
+}
+
+func speakLevel(level int) string {
+	switch level {
+	case domain.MSG_LVL_GOOD:
+		return "good"
+	case domain.MSG_LVL_WARN:
+		return "warning"
+	case domain.MSG_LVL_CRIT:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+func speakDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	hours := minutes / 60
+	minutes = minutes % 60
+
+	switch {
+	case hours == 0 && minutes == 0:
+		return "less than a minute"
+	case hours == 0:
+		return plural(minutes, "minute")
+	case minutes == 0:
+		return plural(hours, "hour")
+	default:
+		return plural(hours, "hour") + " " + plural(minutes, "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,73 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+ * Wraps callback handler with validation of X-Twilio-Signature header.
+ * See: https://www.twilio.com/docs/usage/security#validating-requests
+ */
+func (t *TwilioClient) verified(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		expected := signRequest(t.token, t.publicUrl(r), r.PostForm)
+		if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Twilio-Signature"))) {
+			log.WithField("remote", r.RemoteAddr).WithField("url", r.URL.String()).Warn("twilio: rejecting request with wrong signature")
+			http.Error(w, "wrong signature", http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+/*
+ * Restores url which was requested by twilio.
+ * Scheme and host are taken from 'twiml_url' because fuse can be behind proxy.
+ */
+func (t *TwilioClient) publicUrl(r *http.Request) string {
+	public, err := url.Parse(t.twimlUrl)
+	if err != nil {
+		return r.URL.String()
+	}
+
+	public.Path = r.URL.Path
+	public.RawPath = r.URL.RawPath
+	public.RawQuery = r.URL.RawQuery
+
+	return public.String()
+}
+
+/*
+ * Computes signature: base64(hmac-sha1(url + sorted POST params as key+value)).
+ */
+func signRequest(token string, fullUrl string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := fullUrl
+	for _, key := range keys {
+		for _, value := range params[key] {
+			data += key + value
+		}
+	}
+
+	h := hmac.New(sha1.New, []byte(token))
+	h.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package twilio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTwilioClient() *TwilioClient {
+	options := DefaultTwilioOptions()
+	options.PhoneTo = "+70987654321"
+	options.PhoneFrom = "+71234567890"
+	options.Token = "12345"
+	options.Sid = "ACXXXX"
+	options.TwimlUrl = "https://fuse.example.com/fuse/twiml"
+	return NewTwilioClient(options, domain.NewSchedules())
+}
+
+func TestTwilioKnownSignature(t *testing.T) {
+	// example from twilio documentation
+	params := url.Values{
+		"CallSid": {"CA1234567890ABCDE"},
+		"Caller":  {"+12349013030"},
+		"Digits":  {"1234"},
+		"From":    {"+12349013030"},
+		"To":      {"+18005551212"},
+	}
+
+	signature := signRequest("12345", "https://mycompany.com/myapp.php?foo=1&bar=2", params)
+	assert.Equal(t, "0/KCTR6DLpKmkAf8muzZqo1nDgQ=", signature)
+}
+
+func TestTwilioTwiMLRequiresSignature(t *testing.T) {
+	client := newTestTwilioClient()
+
+	mux := http.NewServeMux()
+	client.ConfigureHTTP(mux, "/fuse")
+
+	params := url.Values{"CallSid": {"CA1234567890ABCDE"}, "CallStatus": {"in-progress"}}
+	matrix := map[string]int{
+		signRequest("12345", "https://fuse.example.com/fuse/twiml", params):       http.StatusOK,
+		signRequest("wrong", "https://fuse.example.com/fuse/twiml", params):       http.StatusForbidden,
+		signRequest("12345", "https://evil.example.com/fuse/twiml", params):       http.StatusForbidden,
+		signRequest("12345", "https://fuse.example.com/fuse/twiml?x=1", params):   http.StatusForbidden,
+		signRequest("12345", "https://fuse.example.com/fuse/twiml", url.Values{}): http.StatusForbidden,
+		"": http.StatusForbidden,
+	}
+
+	for signature, code := range matrix {
+		r := httptest.NewRequest("POST", "/fuse/twiml", strings.NewReader(params.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("X-Twilio-Signature", signature)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		assert.Equalf(t, code, w.Code, "wrong answer for signature %s", signature)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"fuse/pkg/domain"
@@ -22,36 +24,66 @@ type TwilioClient struct {
 	sid   string // api sid
 
 	twimlUrl string // url which returns TwiML document
+
+	reports   map[string]*domain.Incident
+	calls     map[string]*callState // active calling chains by report id
+	mu        sync.RWMutex          // guards reports and calls
+	options   TwilioOptions
+	schedules *domain.Schedules // for resolving "oncall:{name}" phones
 }
 
 type TwiML struct {
 	XMLName xml.Name `xml:"Response"`
 
-	Say  string `xml:",omitempty"`
-	Play string `xml:",omitempty"`
+	Gather *TwiMLGather `xml:",omitempty"`
+	Say    *TwiMLSay    `xml:",omitempty"`
+	Play   string       `xml:",omitempty"`
+}
+
+type TwiMLGather struct {
+	Action    string    `xml:"action,attr"`
+	Method    string    `xml:"method,attr"`
+	NumDigits int       `xml:"numDigits,attr"`
+	Timeout   int       `xml:"timeout,attr"`
+	Say       *TwiMLSay `xml:",omitempty"`
+}
+
+type TwiMLSay struct {
+	Voice    string `xml:"voice,attr,omitempty"`
+	Language string `xml:"language,attr,omitempty"`
+	Text     string `xml:",chardata"`
 }
 
-func NewTwilioClient(phoneTo, phoneFrom, token, sid, twimlUrl string) *TwilioClient {
+func NewTwilioClient(options TwilioOptions, schedules *domain.Schedules) *TwilioClient {
 	return &TwilioClient{
-		phoneTo:   phoneTo,
-		phoneFrom: phoneFrom,
-		token:     token,
-		sid:       sid,
-		twimlUrl:  twimlUrl,
+		phoneTo:   options.PhoneTo,
+		phoneFrom: options.PhoneFrom,
+		token:     options.Token,
+		sid:       options.Sid,
+		twimlUrl:  options.TwimlUrl,
+		reports:   make(map[string]*domain.Incident),
+		calls:     make(map[string]*callState),
+		options:   options,
+		schedules: schedules,
 	}
 }
 
 /*
- * Implements HTTP callback server for slash-command in slack.
+ * Implements HTTP callback which returns TwiML document for call.
+ * Path of 'twiml_url' is public one, so it must already contain basePath.
  */
-func (t *TwilioClient) ConfigureHTTP() {
+func (t *TwilioClient) ConfigureHTTP(mux *http.ServeMux, basePath string) {
 	twimlUrlParsed, err := url.Parse(t.twimlUrl)
 	if err != nil {
 		log.WithField("url", t.twimlUrl).Fatal("twilio: can't parse twimlUrl")
 	}
 
-	http.HandleFunc(twimlUrlParsed.Path, func(w http.ResponseWriter, r *http.Request) {
-		xml, err := t.generateTwiML()
+	if !strings.HasPrefix(twimlUrlParsed.Path, basePath+"/") {
+		log.WithField("url", t.twimlUrl).WithField("base_path", basePath).Warn("twilio: path of twimlUrl is outside of http base path")
+	}
+
+	mux.HandleFunc(twimlUrlParsed.Path, t.verified(func(w http.ResponseWriter, r *http.Request) {
+		xml, err := t.generateTwiML(r.FormValue("report"))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -59,13 +91,44 @@ func (t *TwilioClient) ConfigureHTTP() {
 
 		w.Header().Set("Content-Type", "application/xml")
 		w.Write(xml)
-	})
+	}))
+
+	mux.HandleFunc(twimlUrlParsed.Path+"/gather", t.verified(t.handleGather))
+	mux.HandleFunc(twimlUrlParsed.Path+"/status", t.verified(t.handleStatus))
 }
 
-func (t *TwilioClient) generateTwiML() ([]byte, error) {
-	// TODO: replace with public available mp3
-	twiml := TwiML{Play: "http://ont.pw/tuntrol.mp3"}
+/*
+ * Renders TwiML which describes incident with provided report id.
+ * Falls back to configured mp3 (or generic phrase) if incident is unknown.
+ */
+func (t *TwilioClient) generateTwiML(reportId string) ([]byte, error) {
+	var twiml TwiML
+
+	t.mu.RLock()
+	incident, ok := t.reports[reportId]
+	t.mu.RUnlock()
+
+	switch {
+	case ok:
+		// ask for keypad action and say goodbye if nothing was pressed
+		twiml.Gather = &TwiMLGather{
+			Action:    t.callbackUrl("/gather", reportId),
+			Method:    "POST",
+			NumDigits: 1,
+			Timeout:   10,
+			Say:       t.say(describeIncident(incident) + " " + gatherHint),
+		}
+		twiml.Say = t.say("No input received. Goodbye.")
+	case t.options.PlayUrl != "":
+		twiml.Play = t.options.PlayUrl
+	default:
+		twiml.Say = t.say("Fuse alert. Incident details are not available.")
+	}
 
+	return marshalTwiML(twiml)
+}
+
+func marshalTwiML(twiml TwiML) ([]byte, error) {
 	xml, err := xml.Marshal(twiml)
 	if err != nil {
 		log.WithError(err).Error("twilio: error during TwiML serialization")
@@ -75,20 +138,123 @@ func (t *TwilioClient) generateTwiML() ([]byte, error) {
 	return []byte(xml), nil
 }
 
+func (t *TwilioClient) say(text string) *TwiMLSay {
+	return &TwiMLSay{
+		Voice:    t.options.Voice,
+		Language: t.options.Language,
+		Text:     text,
+	}
+}
+
+/*
+ * Returns url for TwiML document of particular report.
+ */
+func (t *TwilioClient) callUrl(reportId string) string {
+	return t.callbackUrl("", reportId)
+}
+
+/*
+ * Returns public url of callback (path is relative to 'twiml_url') with report id.
+ */
+func (t *TwilioClient) callbackUrl(path string, reportId string) string {
+	u, err := url.Parse(t.twimlUrl)
+	if err != nil {
+		return t.twimlUrl
+	}
+
+	u.Path += path
+	if reportId != "" {
+		query := u.Query()
+		query.Set("report", reportId)
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
 func (t *TwilioClient) GetName() string {
 	return "twilio"
 }
 
+func (t *TwilioClient) Good(msg domain.Message) error {
+	return t.alert(t.options.ModeGood, msg)
+}
+
+func (t *TwilioClient) Warn(msg domain.Message) error {
+	return t.alert(t.options.ModeWarn, msg)
+}
+
 func (t *TwilioClient) Crit(msg domain.Message) error {
-	// Build out the data for our message
+	return t.alert(t.options.ModeCrit, msg)
+}
+
+/*
+ * Sends message via configured mode of level: MODE_CALL, MODE_SMS or MODE_NONE.
+ */
+func (t *TwilioClient) alert(mode string, msg domain.Message) error {
+	if mode == MODE_NONE {
+		return nil
+	}
+
+	t.mu.RLock()
+	incident, ok := t.reports[msg.ReportId]
+	t.mu.RUnlock()
+
+	if ok && incident.IsAcked() && msg.Level != domain.MSG_LVL_GOOD {
+		log.WithField("report", msg.ReportId).WithField("acked_by", incident.GetAckedBy()).Info("twilio: incident is acknowledged, skipping alert")
+		return nil
+	}
+
+	phoneTo := t.schedules.Resolve(t.phoneTo)
+	if phoneTo == "" {
+		return fmt.Errorf("twilio: nobody is on call for '%s'", t.phoneTo)
+	}
+
+	switch mode {
+	case MODE_CALL:
+		return t.dial(msg.ReportId, phoneTo)
+	case MODE_SMS:
+		return t.sms(phoneTo, t.formatSms(msg))
+	default:
+		return fmt.Errorf("twilio: unknown mode '%s'", mode)
+	}
+}
+
+/*
+ * Places call to phone number; TwiML for call will describe report.
+ */
+func (t *TwilioClient) call(phoneTo string, reportId string) error {
 	request := map[string]interface{}{
-		"To":   t.phoneTo,
+		"To":   phoneTo,
 		"From": t.phoneFrom,
-		"Url":  t.twimlUrl,
+		"Url":  t.callUrl(reportId),
 	}
 
+	// ask twilio to inform about call result for retries
+	if reportId != "" {
+		request["StatusCallback"] = t.callbackUrl("/status", reportId)
+	}
+
+	return t.post("Calls.json", request)
+}
+
+/*
+ * Sends text message to phone number.
+ */
+func (t *TwilioClient) sms(phoneTo string, text string) error {
+	return t.post("Messages.json", map[string]interface{}{
+		"To":   phoneTo,
+		"From": t.phoneFrom,
+		"Body": text,
+	})
+}
+
+/*
+ * Sends request to account's resource of twilio api.
+ */
+func (t *TwilioClient) post(resource string, request map[string]interface{}) error {
 	res, _, errs := gorequest.New().
-		Post("https://api.twilio.com/2010-04-01/Accounts/"+t.sid+"/Calls.json").
+		Post(strings.TrimRight(t.options.ApiUrl, "/")+"/2010-04-01/Accounts/"+t.sid+"/"+resource).
 		SetBasicAuth(t.sid, t.token).
 		Type(gorequest.TypeForm).
 		Send(request).
@@ -114,18 +280,19 @@ func (t *TwilioClient) Crit(msg domain.Message) error {
 	return nil
 }
 
-func (t *TwilioClient) Good(msg domain.Message) error {
-	return nil
-}
+func (t *TwilioClient) Report(incident *domain.Incident) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-func (t *TwilioClient) Warn(msg domain.Message) error {
+	t.reports[incident.Id] = incident
 	return nil
 }
 
-func (t *TwilioClient) Report(reportId string, msg domain.Message) error {
-	return nil
-}
+func (t *TwilioClient) Resolve(incident *domain.Incident) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-func (t *TwilioClient) Resolve(reportId string) error {
+	delete(t.reports, incident.Id)
+	delete(t.calls, incident.Id)
 	return nil
 }
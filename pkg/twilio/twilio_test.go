@@ -0,0 +1,339 @@
+package twilio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"fuse/pkg/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwilioCallUrlCarriesReportId(t *testing.T) {
+	client := newTestTwilioClient()
+
+	assert.Equal(t, "https://fuse.example.com/fuse/twiml?report=abc12", client.callUrl("abc12"))
+	assert.Equal(t, "https://fuse.example.com/fuse/twiml", client.callUrl(""))
+}
+
+func TestTwilioTwiMLDescribesIncident(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.Voice = "Polly.Joanna"
+
+	incidents := domain.NewIncidents()
+	incident := incidents.Open("abc12", domain.Message{
+		Level: domain.MSG_LVL_CRIT,
+		Title: "SERVICE: grafana in CRIT state",
+	})
+	incident.Since = time.Now().Add(-65 * time.Minute)
+	client.Report(incident)
+
+	xml, err := client.generateTwiML("abc12")
+	assert.NoError(t, err)
+	assert.Equal(t, `<Response>`+
+		`<Gather action="https://fuse.example.com/fuse/twiml/gather?report=abc12" method="POST" numDigits="1" timeout="10">`+
+		`<Say voice="Polly.Joanna" language="en-US">Fuse alert. Level critical. SERVICE: grafana in CRIT state. Lasting for 1 hour 5 minutes. `+gatherHint+`</Say>`+
+		`</Gather>`+
+		`<Say voice="Polly.Joanna" language="en-US">No input received. Goodbye.</Say>`+
+		`</Response>`, string(xml))
+
+	client.Resolve(incident)
+	xml, err = client.generateTwiML("abc12")
+	assert.NoError(t, err)
+	assert.Contains(t, string(xml), "Incident details are not available")
+
+	client.options.PlayUrl = "https://example.com/alarm.mp3"
+	xml, err = client.generateTwiML("unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, `<Response><Play>https://example.com/alarm.mp3</Play></Response>`, string(xml))
+}
+
+func TestTwilioSpeakDuration(t *testing.T) {
+	matrix := map[time.Duration]string{
+		30 * time.Second:  "less than a minute",
+		time.Minute:       "1 minute",
+		12 * time.Minute:  "12 minutes",
+		2 * time.Hour:     "2 hours",
+		121 * time.Minute: "2 hours 1 minute",
+	}
+	for d, text := range matrix {
+		assert.Equal(t, text, speakDuration(d))
+	}
+}
+
+func gather(client *TwilioClient, reportId, phone, digits string) string {
+	mux := http.NewServeMux()
+	client.ConfigureHTTP(mux, "/fuse")
+
+	gatherUrl := client.callbackUrl("/gather", reportId)
+	params := url.Values{"Digits": {digits}, "To": {phone}}
+
+	r := httptest.NewRequest("POST", gatherUrl, strings.NewReader(params.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Twilio-Signature", signRequest(client.token, gatherUrl, params))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w.Body.String()
+}
+
+func TestTwilioGatherAcknowledge(t *testing.T) {
+	client := newTestTwilioClient()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{Level: domain.MSG_LVL_CRIT})
+	client.Report(incident)
+
+	assert.Contains(t, gather(client, "abc12", "+70987654321", "1"), "Incident acknowledged")
+	assert.True(t, incident.IsAcked())
+	assert.Equal(t, "phone +70987654321", incident.GetAckedBy())
+
+	assert.Contains(t, gather(client, "abc12", "+70987654321", "1"), "already acknowledged")
+	assert.Contains(t, gather(client, "abc12", "+70987654321", "9"), "Unknown option")
+	assert.Contains(t, gather(client, "abc12", "+70987654321", "2"), "nobody to escalate")
+	assert.Contains(t, gather(client, "other", "+70987654321", "1"), "already resolved")
+
+	// acknowledged incident must not produce calls
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+}
+
+func TestTwilioEscalationChain(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.EscalateTo = []string{"+71111111111", "+72222222222"}
+
+	assert.Equal(t, "+71111111111", client.nextPhone("+70987654321"))
+	assert.Equal(t, "+72222222222", client.nextPhone("+71111111111"))
+	assert.Equal(t, "", client.nextPhone("+72222222222"))
+	assert.Equal(t, "", client.nextPhone("+79999999999"))
+}
+
+type twilioRequest struct {
+	Path   string
+	Params url.Values
+}
+
+/*
+ * Points client to local http server which records all requests to twilio api.
+ */
+func serveTwilioApi(client *TwilioClient) (*httptest.Server, chan twilioRequest) {
+	requests := make(chan twilioRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests <- twilioRequest{r.URL.Path, r.PostForm}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	client.options.ApiUrl = server.URL
+	return server, requests
+}
+
+func TestTwilioModes(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.ModeWarn = MODE_SMS
+	client.options.ModeGood = MODE_SMS
+
+	server, requests := serveTwilioApi(client)
+	defer server.Close()
+
+	msg := domain.Message{
+		Level:    domain.MSG_LVL_WARN,
+		ReportId: "abc12",
+		Title:    "QUERY: some route in WARN state",
+		Body:     "Query has bad value for more than 5s.",
+		Query:    "select count(*) from http",
+		Details:  map[string]string{"value": "3"},
+	}
+
+	assert.NoError(t, client.Warn(msg))
+	req := <-requests
+	assert.Equal(t, "/2010-04-01/Accounts/ACXXXX/Messages.json", req.Path)
+	assert.Equal(t, "+70987654321", req.Params.Get("To"))
+	assert.Equal(t, "+71234567890", req.Params.Get("From"))
+	assert.Equal(t, "[WARN] QUERY: some route in WARN state\nvalue: 3\nQuery has bad value for more than 5s.\nreport: abc12", req.Params.Get("Body"))
+
+	msg.Level = domain.MSG_LVL_CRIT
+	assert.NoError(t, client.Crit(msg))
+	req = <-requests
+	assert.Equal(t, "/2010-04-01/Accounts/ACXXXX/Calls.json", req.Path)
+	assert.Equal(t, "https://fuse.example.com/fuse/twiml?report=abc12", req.Params.Get("Url"))
+
+	client.options.ModeGood = MODE_NONE
+	assert.NoError(t, client.Good(msg))
+	assert.Len(t, requests, 0, "nothing must be sent in 'none' mode")
+}
+
+func TestTwilioApiErrors(t *testing.T) {
+	client := newTestTwilioClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code": 20003, "message": "Authenticate"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client.options.ApiUrl = server.URL
+	assert.Error(t, client.Crit(domain.Message{ReportId: "abc12"}))
+}
+
+func TestTwilioSmsIsTruncated(t *testing.T) {
+	client := newTestTwilioClient()
+	text := client.formatSms(domain.Message{Level: domain.MSG_LVL_WARN, Title: "title", Body: strings.Repeat("очень длинно ", 100)})
+	assert.Equal(t, SMS_MAX_LENGTH, len([]rune(text)))
+	assert.True(t, strings.HasSuffix(text, "..."))
+}
+
+func TestTwilioCallsOnCallPerson(t *testing.T) {
+	client := newTestTwilioClient()
+	client.phoneTo = "oncall:primary"
+	client.options.EscalateTo = []string{"oncall:secondary"}
+
+	primary := domain.NewSchedule("primary")
+	primary.Members = []string{"+70000000001"}
+	client.schedules.Add(primary)
+
+	secondary := domain.NewSchedule("secondary")
+	secondary.Members = []string{"+70000000002"}
+	client.schedules.Add(secondary)
+
+	server, requests := serveTwilioApi(client)
+	defer server.Close()
+
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+	assert.Equal(t, "+70000000001", (<-requests).Params.Get("To"))
+	assert.Equal(t, "+70000000002", client.nextPhone("+70000000001"))
+
+	primary.AddOverride("+70000000003", time.Now(), time.Now().Add(time.Hour))
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+	assert.Equal(t, "+70000000003", (<-requests).Params.Get("To"))
+}
+
+func callStatus(client *TwilioClient, reportId, phone, status string) {
+	mux := http.NewServeMux()
+	client.ConfigureHTTP(mux, "/fuse")
+
+	statusUrl := client.callbackUrl("/status", reportId)
+	params := url.Values{"CallStatus": {status}, "To": {phone}}
+
+	r := httptest.NewRequest("POST", statusUrl, strings.NewReader(params.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Twilio-Signature", signRequest(client.token, statusUrl, params))
+
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestTwilioRetriesUntilAcknowledged(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.Retries = 1
+	client.options.RetryDelay = 0
+	client.options.EscalateTo = []string{"+71111111111"}
+
+	server, requests := serveTwilioApi(client)
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{Level: domain.MSG_LVL_CRIT})
+	client.Report(incident)
+
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+	req := <-requests
+	assert.Equal(t, "+70987654321", req.Params.Get("To"))
+	assert.Equal(t, "https://fuse.example.com/fuse/twiml/status?report=abc12", req.Params.Get("StatusCallback"))
+
+	callStatus(client, "abc12", "+70987654321", "no-answer")
+	assert.Equal(t, "+70987654321", (<-requests).Params.Get("To"), "call must be retried")
+
+	callStatus(client, "abc12", "+70987654321", "busy")
+	assert.Equal(t, "+71111111111", (<-requests).Params.Get("To"), "next phone must be called after retries")
+
+	callStatus(client, "abc12", "+70987654321", "no-answer")
+	assert.Len(t, requests, 0, "status of outdated call must be ignored")
+
+	callStatus(client, "abc12", "+71111111111", "completed")
+	assert.Equal(t, "+71111111111", (<-requests).Params.Get("To"), "call without acknowledgement (e.g. voicemail) must be retried")
+
+	gather(client, "abc12", "+71111111111", "1")
+	callStatus(client, "abc12", "+71111111111", "completed")
+	callStatus(client, "abc12", "+71111111111", "no-answer")
+	assert.Len(t, requests, 0, "acknowledged incident must stop the chain")
+
+	events := make([]string, 0)
+	for _, event := range incident.GetEvents() {
+		events = append(events, event.Text)
+	}
+	assert.Equal(t, []string{
+		"call attempt 1 to +70987654321",
+		"call attempt 1 to +70987654321: no-answer",
+		"call attempt 2 to +70987654321",
+		"call attempt 2 to +70987654321: busy",
+		"call attempt 1 to +71111111111",
+		"call attempt 1 to +71111111111: completed",
+		"call attempt 2 to +71111111111",
+		"acknowledged by phone +71111111111",
+		"call attempt 2 to +71111111111: completed",
+	}, events)
+}
+
+func TestTwilioStopsWhenNobodyAnswers(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.Retries = 0
+	client.options.RetryDelay = 0
+
+	server, requests := serveTwilioApi(client)
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{Level: domain.MSG_LVL_CRIT})
+	client.Report(incident)
+
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+	<-requests
+
+	callStatus(client, "abc12", "+70987654321", "failed")
+	assert.Len(t, requests, 0)
+
+	events := incident.GetEvents()
+	assert.Equal(t, "nobody answered the call", events[len(events)-1].Text)
+}
+
+func TestTwilioRetryIsCancelledByResolve(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.RetryDelay = 50 * time.Millisecond
+
+	server, requests := serveTwilioApi(client)
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{Level: domain.MSG_LVL_CRIT})
+	client.Report(incident)
+
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+	<-requests
+
+	callStatus(client, "abc12", "+70987654321", "no-answer")
+	client.Resolve(incident)
+
+	time.Sleep(3 * client.options.RetryDelay)
+	assert.Len(t, requests, 0, "resolved incident must not be called")
+}
+
+func TestTwilioRetryIsCancelledByNewChain(t *testing.T) {
+	client := newTestTwilioClient()
+	client.options.RetryDelay = 50 * time.Millisecond
+	client.options.EscalateTo = []string{"+71111111111"}
+
+	server, requests := serveTwilioApi(client)
+	defer server.Close()
+
+	incident := domain.NewIncidents().Open("abc12", domain.Message{Level: domain.MSG_LVL_CRIT})
+	client.Report(incident)
+
+	assert.NoError(t, client.Crit(domain.Message{ReportId: "abc12"}))
+	<-requests
+
+	// retry of the first phone is pending while chain is escalated to the next one
+	callStatus(client, "abc12", "+70987654321", "no-answer")
+	assert.NoError(t, client.dial("abc12", "+71111111111"))
+	assert.Equal(t, "+71111111111", (<-requests).Params.Get("To"))
+
+	time.Sleep(3 * client.options.RetryDelay)
+	assert.Len(t, requests, 0, "outdated retry must not be called in parallel")
+}